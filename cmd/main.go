@@ -7,14 +7,17 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 
 	"go-misc/internal/cache"
+	"go-misc/internal/errors"
 	grpcmw "go-misc/internal/grpc"
 	"go-misc/internal/grpc/pb"
 	"go-misc/internal/hello"
+	hellocached "go-misc/internal/hello/cached"
 	hellogrpc "go-misc/internal/hello/grpc"
 	hellohttp "go-misc/internal/hello/http"
 	helloinmem "go-misc/internal/hello/inmem"
@@ -23,6 +26,7 @@ import (
 	"go-misc/internal/validator"
 
 	"github.com/gorilla/mux"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -40,15 +44,28 @@ func main() {
 		// 	"env":     "dev",
 		// }),
 	)
+	errors.SetCapture(errors.CaptureCaller)
 	v := validator.NewValidator()
-	c, err := cache.NewCache(signalCtx)
+	// no exporter: spans are only used for propagating and logging trace IDs,
+	// register a sdktrace.WithBatcher(exporter) for sending them to a collector.
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	// the cache outlives the servers, it is snapshotted once they are drained
+	cacheCtx, cacheCancel := context.WithCancel(context.Background())
+	c, err := cache.NewCache[string, hello.Message](cacheCtx,
+		cache.WithName("hello"),
+		cache.WithMetrics(cache.WithNamespace("hello")),
+		// warm start after a restart
+		cache.WithRestoreFile(filepath.Join(os.TempDir(), "hello.cache")),
+		cache.WithSnapshotFile(filepath.Join(os.TempDir(), "hello.cache")),
+	)
 	if err != nil {
 		l.Error("error creating cache", "err", err.Error())
 		os.Exit(1)
 	}
 
-	helloRepo := helloinmem.NewRepository()
-	helloService := hello.NewService(l, v, c, helloRepo)
+	helloRepo := hellocached.NewRepository(c, helloinmem.NewRepository())
+	helloService := hello.NewService(l, v, helloRepo)
 
 	var wg sync.WaitGroup
 	go func() {
@@ -67,7 +84,10 @@ func main() {
 		r := mux.NewRouter()
 		r.Use(
 			httpmw.ContentType,
-			httpmw.RequestID, //before logger
+			httpmw.Metrics(httpmw.WithNamespace("hello")), // before recover
+			httpmw.RequestID(), //before logger
+			httpmw.Locale,
+			httpmw.Trace(httpmw.WithTracerProvider(tp)), // before logger
 			httpmw.Logger(
 				httpmw.WithLogger(httpl),
 				httpmw.WithConcise(true),
@@ -116,10 +136,13 @@ func main() {
 	wg.Add(1)
 	go func() {
 		grpcl := l.With(slog.String("transport", "grpc"))
+		grpcm := grpcmw.NewMetrics(grpcmw.WithNamespace("hello"))
 		grpcsrv := grpc.NewServer(
 			grpc.ChainUnaryInterceptor(
-				grpcmw.RequestIDUnaryServerInterceptor, // before logger
-				grpcmw.ErrorUnaryServerInterceptor,     // before logger
+				grpcmw.RequestIDUnaryServerInterceptor(),                          // before logger
+				grpcmw.ErrorUnaryServerInterceptor,                                // before logger
+				grpcmw.TraceUnaryServerInterceptor(grpcmw.WithTracerProvider(tp)), // before logger
+				grpcm.UnaryServerInterceptor,                                      // before recover
 				grpcmw.LoggerUnaryServerInterceptor(
 					grpcmw.WithLogger(grpcl),
 					grpcmw.WithConcise(true),
@@ -131,6 +154,18 @@ func main() {
 				),
 				grpcmw.RecoverUnaryServerInterceptor, // after logger
 			),
+			grpc.ChainStreamInterceptor(
+				grpcmw.RequestIDStreamServerInterceptor(),                          // before logger
+				grpcmw.ErrorStreamServerInterceptor,                                // before logger
+				grpcmw.TraceStreamServerInterceptor(grpcmw.WithTracerProvider(tp)), // before logger
+				grpcm.StreamServerInterceptor,                                      // before recover
+				grpcmw.LoggerStreamServerInterceptor(
+					grpcmw.WithLogger(grpcl),
+					grpcmw.WithConcise(true),
+					grpcmw.WithLeak(false),
+				),
+				grpcmw.RecoverStreamServerInterceptor, // after logger
+			),
 		)
 		pb.RegisterHelloServiceServer(grpcsrv, hellogrpc.NewServer(grpcl, helloService))
 
@@ -187,4 +222,6 @@ func main() {
 	l.Info("started")
 
 	wg.Wait()
+	cacheCancel()
+	<-c.Done() // snapshot written
 }
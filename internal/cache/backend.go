@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// Backend stores the serialized entries of a Cache by their string key.
+// It must be safe for concurrent use.
+type Backend interface {
+	// Get returns a copy of the entry of key, false if there is none.
+	Get(key string) ([]byte, bool)
+	// Set inserts or replaces the entry of key, entry must not be modified afterwards.
+	Set(key string, entry []byte) error
+	// Delete removes key, false if there is none. On error, e.g. from a remote backend, the key may still be there.
+	Delete(key string) (bool, error)
+	// Iterate calls fn for every entry until fn returns false, fn may use the backend.
+	Iterate(fn func(key string, entry []byte) bool) error
+	// Len returns the number of entries.
+	Len() int
+	// Capacity returns the size of the entries in bytes.
+	Capacity() int
+	Stats() Stats
+	// Reset removes all the entries, without calling OnRemove.
+	Reset() error
+	// Close stops the background work of the backend and releases its resources, the cache calls it once its context is done.
+	Close() error
+}
+
+// MultiGetter is implemented by the backends getting several entries at once more efficiently than one by one,
+// e.g. in a single round trip. See Cache.GetMany.
+type MultiGetter interface {
+	// GetMany returns copies of the entries of keys, in the same order, nil for the missing ones.
+	GetMany(keys []string) [][]byte
+}
+
+// getMany gets keys from b, with a single call if b is a MultiGetter.
+func getMany(b Backend, keys []string) [][]byte {
+	if mg, ok := b.(MultiGetter); ok {
+		return mg.GetMany(keys)
+	}
+	entries := make([][]byte, len(keys))
+	for i, k := range keys {
+		if entry, ok := b.Get(k); ok {
+			entries[i] = entry
+		}
+	}
+	return entries
+}
+
+// Stats are the counters of a Backend exported by the metrics.
+type Stats struct {
+	Collisions   int64 // keys with the same hash
+	DeleteHits   int64
+	DeleteMisses int64
+}
+
+// BackendConfig is the configuration given by NewCache to a BackendFactory, from the options of the cache.
+type BackendConfig struct {
+	Shards          int
+	TTL             time.Duration // time after which an entry can be evicted
+	CleanWindow     time.Duration // interval between the removals of the expired entries, 0 disables them
+	MaxSizeMB       int           // 0 means no limit
+	ExpectedEntries int
+	Logger          *slog.Logger // may be nil
+
+	// OnRemove must be called with a removed entry, except on Reset.
+	// It may be called under the lock of the backend, it does not use the backend.
+	OnRemove func(key string, entry []byte, reason Reason)
+}
+
+// BackendFactory creates a Backend, it is stopped when ctx is done. See WithBackend.
+type BackendFactory func(ctx context.Context, config BackendConfig) (Backend, error)
+
+// BigCache is the default backend: entries are evicted in FIFO order after the TTL or when the cache is full,
+// with a one second resolution.
+func BigCache(ctx context.Context, cfg BackendConfig) (Backend, error) {
+	// When cache load can be predicted in advance then it is better to use custom initialization
+	// because additional memory allocation can be avoided in that way.
+	config := bigcache.Config{
+		// number of shards (must be a power of 2)
+		Shards: cfg.Shards,
+
+		// time after which entry can be evicted
+		LifeWindow: cfg.TTL,
+
+		// Interval between removing expired entries (clean up).
+		// If set to <= 0 then no action is performed.
+		// Setting to < 1 second is counterproductive — bigcache has a one second resolution.
+		CleanWindow: cfg.CleanWindow,
+
+		// rps * lifeWindow, used only in initial memory allocation
+		MaxEntriesInWindow: cfg.ExpectedEntries,
+
+		// max entry size in bytes, used only in initial memory allocation
+		MaxEntrySize: 500,
+
+		// prints information about additional memory allocation
+		Verbose: cfg.Logger != nil,
+		Logger:  logger{cfg.Logger},
+
+		// cache will not allocate more memory than this limit, value in MB
+		// if value is reached then the oldest entries can be overridden for the new ones
+		// 0 value means no size limit
+		HardMaxCacheSize: cfg.MaxSizeMB,
+
+		// callback fired when the oldest entry is removed because of its expiration time or no space left
+		// for the new entry, or because delete was called. A bitmask representing the reason will be returned.
+		// Default value is nil which means no callback and it prevents from unwrapping the oldest entry.
+		OnRemove: nil,
+
+		// OnRemoveWithReason is a callback fired when the oldest entry is removed because of its expiration time or no space left
+		// for the new entry, or because delete was called. A constant representing the reason will be passed through.
+		// Default value is nil which means no callback and it prevents from unwrapping the oldest entry.
+		// Ignored if OnRemove is specified.
+		OnRemoveWithReason: nil,
+	}
+	if cfg.OnRemove != nil {
+		config.OnRemoveWithReason = func(key string, entry []byte, reason bigcache.RemoveReason) {
+			cfg.OnRemove(key, entry, Reason(reason))
+		}
+	}
+
+	c, err := bigcache.New(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return bigcacheBackend{c}, nil
+}
+
+type bigcacheBackend struct {
+	c *bigcache.BigCache
+}
+
+func (b bigcacheBackend) Get(key string) ([]byte, bool) {
+	entry, err := b.c.Get(key)
+	return entry, err == nil
+}
+
+func (b bigcacheBackend) Set(key string, entry []byte) error {
+	return b.c.Set(key, entry)
+}
+
+// Delete only fails with bigcache.ErrEntryNotFound.
+func (b bigcacheBackend) Delete(key string) (bool, error) {
+	return b.c.Delete(key) == nil, nil
+}
+
+func (b bigcacheBackend) Iterate(fn func(key string, entry []byte) bool) error {
+	it := b.c.Iterator()
+	for it.SetNext() {
+		info, err := it.Value()
+		if err != nil {
+			return err
+		}
+		if !fn(info.Key(), info.Value()) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (b bigcacheBackend) Len() int {
+	return b.c.Len()
+}
+
+func (b bigcacheBackend) Capacity() int {
+	return b.c.Capacity()
+}
+
+func (b bigcacheBackend) Stats() Stats {
+	s := b.c.Stats()
+	return Stats{Collisions: s.Collisions, DeleteHits: s.DelHits, DeleteMisses: s.DelMisses}
+}
+
+func (b bigcacheBackend) Reset() error {
+	return b.c.Reset()
+}
+
+func (b bigcacheBackend) Close() error {
+	return b.c.Close()
+}
+
+// logger adapts slog.Logger to bigcache.Logger.
+type logger struct {
+	l *slog.Logger
+}
+
+func (l logger) Printf(format string, v ...any) {
+	if l.l != nil {
+		l.l.Debug(fmt.Sprintf(format, v...))
+	}
+}
+
+// errTooLarge is returned by the in-process backends for an entry larger than the cache.
+var errTooLarge = errors.New("entry larger than the cache")
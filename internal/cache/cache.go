@@ -1,118 +1,480 @@
 package cache
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"log/slog"
+	"sync/atomic"
 	"time"
 
-	"github.com/allegro/bigcache/v3"
+	"golang.org/x/sync/singleflight"
 )
 
-type Cache struct {
-	cache *bigcache.BigCache
+// Cache is a typed cache of V values by K keys, stored serialized in a Backend, BigCache by default.
+//
+// Keys are stored as strings: string keys as is, any other key with fmt.Sprint,
+// so distinct keys must have distinct representations.
+// Values are serialized by the Codec of the cache, Gob by default.
+type Cache[K comparable, V any] struct {
+	b     Backend
+	ttl   time.Duration
+	codec Codec
+	group singleflight.Group // GetOrLoad
+	// writes counts the writes by stripe of keys (see stripe), a load does not cache its result over a write
+	writes [64]atomic.Uint64
+	seed   maphash.Seed
+	m      *metrics // nil if disabled
+	subs   subscriptions
+	done   <-chan struct{} // ends the subscriptions
+	l      *slog.Logger
+	// closed once the context of the cache is done, the snapshot written (see WithSnapshotFile) and the backend closed
+	closed chan struct{}
 }
 
-// newBigCache returns a new BigCache struct
-func NewCache(ctx context.Context) (*Cache, error) {
-	// When cache load can be predicted in advance then it is better to use custom initialization
-	// because additional memory allocation can be avoided in that way.
-	config := bigcache.Config{
-		// number of shards (must be a power of 2)
-		Shards: 128,
+// Reason is why an entry has been removed from the cache.
+type Reason int
 
-		// time after which entry can be evicted
-		LifeWindow: 5 * time.Minute,
+const (
+	// the entry is past its TTL
+	Expired Reason = iota + 1
+	// the entry was the oldest and the cache was full
+	NoSpace
+	// Delete has been called
+	Deleted
+)
 
-		// Interval between removing expired entries (clean up).
-		// If set to <= 0 then no action is performed.
-		// Setting to < 1 second is counterproductive — bigcache has a one second resolution.
-		CleanWindow: 1 * time.Minute,
+func (r Reason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case NoSpace:
+		return "no_space"
+	case Deleted:
+		return "deleted"
+	}
+	return "unknown"
+}
 
-		// rps * lifeWindow, used only in initial memory allocation
-		MaxEntriesInWindow: 1000 * 10 * 60,
+type options struct {
+	// name identifies the cache in the metrics.
+	name string
 
-		// max entry size in bytes, used only in initial memory allocation
-		MaxEntrySize: 500,
+	// shards is the number of shards, it must be a power of two.
+	shards int
 
-		// prints information about additional memory allocation
-		Verbose: false,
+	// ttl is the time after which an entry expires, unless set with another TTL, and is evicted by BigCache.
+	ttl time.Duration
 
-		// cache will not allocate more memory than this limit, value in MB
-		// if value is reached then the oldest entries can be overridden for the new ones
-		// 0 value means no size limit
-		HardMaxCacheSize: 128,
+	// cleanWindow is the interval between the removals of the expired entries, 0 disables them
+	// (expired entries are then only overwritten). Less than a second is counterproductive,
+	// bigcache has a one second resolution.
+	cleanWindow time.Duration
 
-		// callback fired when the oldest entry is removed because of its expiration time or no space left
-		// for the new entry, or because delete was called. A bitmask representing the reason will be returned.
-		// Default value is nil which means no callback and it prevents from unwrapping the oldest entry.
-		OnRemove: nil,
+	// maxSizeMB is the maximum size of the cache in MB, the oldest entries are overwritten
+	// when it is reached. 0 means no limit.
+	maxSizeMB int
 
-		// OnRemoveWithReason is a callback fired when the oldest entry is removed because of its expiration time or no space left
-		// for the new entry, or because delete was called. A constant representing the reason will be passed through.
-		// Default value is nil which means no callback and it prevents from unwrapping the oldest entry.
-		// Ignored if OnRemove is specified.
-		OnRemoveWithReason: nil,
-	}
+	// expectedEntries is the expected number of entries (rps * ttl), used only for the initial memory allocation.
+	expectedEntries int
 
-	c, err := bigcache.New(ctx, config)
-	if err != nil {
-		return &Cache{}, err
-	}
-	return &Cache{cache: c}, nil
+	// l logs the memory allocations and the key collisions at debug level, nothing is logged if nil,
+	// and the snapshot errors, with slog.Default() if nil.
+	l *slog.Logger
+
+	// onEvict is called with the key and the reason when an entry is removed.
+	onEvict func(key string, reason Reason)
+
+	// codec serializes the values, see Codec.
+	codec Codec
+
+	// backend stores the entries, see Backend.
+	backend BackendFactory
+
+	// metrics are the options of the metrics, nil if disabled.
+	metrics []MetricsOption
+
+	// restoreFile is the snapshot restored at construction, if any.
+	restoreFile string
+
+	// snapshotFile is where the cache is snapshotted when its context is done, if any.
+	snapshotFile string
 }
 
-// Set inserts the key/value pair into the cache.
-// Only the exported fields of the given struct will be
-// serialized and stored
-func (c *Cache) Set(key string, value interface{}) error {
-	valueBytes, err := encode(value)
-	if err != nil {
-		return err
+type Option func(*options)
+
+func evaluateOptions(opts []Option) *options {
+	opt := &options{
+		name:            "default",
+		shards:          128,
+		ttl:             5 * time.Minute,
+		cleanWindow:     1 * time.Minute,
+		maxSizeMB:       128,
+		expectedEntries: 1000 * 10 * 60,
+		codec:           Gob,
+		backend:         BigCache,
+	}
+	for _, o := range opts {
+		o(opt)
 	}
+	return opt
+}
 
-	return c.cache.Set(key, valueBytes)
+func (o *options) validate() error {
+	var errs []error
+	if o.shards <= 0 || o.shards&(o.shards-1) != 0 {
+		errs = append(errs, fmt.Errorf("shards must be a power of two, got %d", o.shards))
+	}
+	if o.ttl <= 0 {
+		errs = append(errs, fmt.Errorf("ttl must be positive, got %s", o.ttl))
+	}
+	if o.cleanWindow < 0 {
+		errs = append(errs, fmt.Errorf("clean window must be positive or 0, got %s", o.cleanWindow))
+	}
+	if o.maxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("max size must be positive or 0, got %dMB", o.maxSizeMB))
+	}
+	if o.expectedEntries <= 0 {
+		errs = append(errs, fmt.Errorf("expected entries must be positive, got %d", o.expectedEntries))
+	}
+	if o.codec == nil {
+		errs = append(errs, errors.New("codec must not be nil"))
+	}
+	if o.backend == nil {
+		errs = append(errs, errors.New("backend must not be nil"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("cache: invalid options: %w", err)
+	}
+	return nil
 }
 
-// Get returns the value correlating to the key in the cache
-func (c *Cache) Get(key string) (interface{}, error) {
-	// Get the value in the byte format it is stored in
-	valueBytes, err := c.cache.Get(key)
-	if err != nil {
+// NewCache returns a new Cache backed by its own Backend, the options are validated.
+func NewCache[K comparable, V any](ctx context.Context, opts ...Option) (*Cache[K, V], error) {
+	o := evaluateOptions(opts)
+	if err := o.validate(); err != nil {
 		return nil, err
 	}
 
-	// Deserialize the bytes of the value
-	value, err := decode(valueBytes)
+	c := &Cache[K, V]{
+		ttl:    o.ttl,
+		codec:  o.codec,
+		subs:   subscriptions{subs: make(map[*subscription]struct{})},
+		done:   ctx.Done(),
+		seed:   maphash.MakeSeed(),
+		l:      o.l,
+		closed: make(chan struct{}),
+	}
+	if c.l == nil {
+		c.l = slog.Default()
+	}
+	if o.metrics != nil {
+		c.m = newMetrics(o.name, o.metrics)
+	}
+
+	var err error
+	c.b, err = o.backend(ctx, BackendConfig{
+		Shards:          o.shards,
+		TTL:             o.ttl,
+		CleanWindow:     o.cleanWindow,
+		MaxSizeMB:       o.maxSizeMB,
+		ExpectedEntries: o.expectedEntries,
+		Logger:          o.l,
+		// for the metrics, WithOnEvict and Subscribe
+		OnRemove: func(key string, entry []byte, reason Reason) {
+			c.onRemove(key, entry, reason, o.onEvict)
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
+	// the server is never scanned, see Remote
+	if _, ok := c.b.(*remote); ok && (o.snapshotFile != "" || o.restoreFile != "") {
+		c.b.Close()
+		return nil, errors.New("cache: invalid options: snapshot and restore files are not supported by the remote backend")
+	}
+	if c.m != nil {
+		if err := c.m.register(c.b); err != nil {
+			c.b.Close()
+			return nil, fmt.Errorf("cache %s: register metrics: %w", o.name, err)
+		}
+	}
+
+	// a cache is only an optimization, it starts even if its snapshot is unusable
+	if o.restoreFile != "" {
+		if err := c.restoreFile(o.restoreFile); err != nil {
+			c.l.Error("error restoring cache", "cache", o.name, "err", err.Error())
+		}
+	}
+	go func() {
+		defer close(c.closed)
+		<-ctx.Done()
+		if o.snapshotFile != "" {
+			if err := c.snapshotFile(o.snapshotFile); err != nil {
+				c.l.Error("error snapshotting cache", "cache", o.name, "err", err.Error())
+			}
+		}
+		if err := c.b.Close(); err != nil {
+			c.l.Error("error closing cache backend", "cache", o.name, "err", err.Error())
+		}
+	}()
+	return c, nil
+}
+
+// Done returns a channel closed once the context of the cache is done, its snapshot written (see WithSnapshotFile)
+// and its backend closed, the program should wait for it before exiting.
+func (c *Cache[K, V]) Done() <-chan struct{} {
+	return c.closed
+}
+
+// WithName names the cache ("default" by default), it is the "cache" label of the metrics.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
 
-	return value, nil
+// WithMetrics exports the metrics of the cache to Prometheus: hits, misses, collisions, deletes, entries, capacity,
+// evictions by reason and the errors and duration of the codec. The name of the cache must be unique (see WithName).
+func WithMetrics(opts ...MetricsOption) Option {
+	return func(o *options) {
+		o.metrics = append([]MetricsOption{}, opts...)
+	}
 }
 
-func encode(value interface{}) ([]byte, error) {
-	buf := bytes.Buffer{}
-	enc := gob.NewEncoder(&buf)
-	gob.Register(value)
+// WithShards sets the number of shards, a power of two (128 by default).
+// More shards means less lock contention but more memory.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithTTL sets the time after which an entry expires (5m by default), it is the maximum TTL of SetWithTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithCleanWindow sets the interval between the removals of the expired entries (1m by default), 0 disables them.
+func WithCleanWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.cleanWindow = d
+	}
+}
+
+// WithMaxSizeMB sets the maximum size of the cache in MB (128 by default), 0 means no limit.
+func WithMaxSizeMB(mb int) Option {
+	return func(o *options) {
+		o.maxSizeMB = mb
+	}
+}
+
+// WithExpectedEntries sets the expected number of entries (600000 by default), for the initial memory allocation.
+func WithExpectedEntries(n int) Option {
+	return func(o *options) {
+		o.expectedEntries = n
+	}
+}
 
-	err := enc.Encode(&value)
+// WithLogger logs the memory allocations and the key collisions at debug level, and the snapshot errors.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.l = l
+	}
+}
+
+// WithOnEvict sets a callback called with the key (as stored, see Cache) and the reason when an entry is removed.
+// It is called under the lock of the shard of the entry, it must be fast and must not use the cache,
+// see Subscribe for an asynchronous and typed alternative.
+func WithOnEvict(fn func(key string, reason Reason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+// WithRestoreFile restores the snapshot at path (see Restore) at construction, a missing file is ignored
+// and a bad one is logged. The Remote backend does not support it, TwoTier restores its local backend.
+func WithRestoreFile(path string) Option {
+	return func(o *options) {
+		o.restoreFile = path
+	}
+}
+
+// WithSnapshotFile writes a snapshot at path (see Snapshot) when the context of the cache is done, see Done.
+// The Remote backend does not support it, TwoTier snapshots its local backend.
+func WithSnapshotFile(path string) Option {
+	return func(o *options) {
+		o.snapshotFile = path
+	}
+}
+
+// WithBackend sets the backend storing the entries: BigCache (default), LRU, TinyLFU, Remote, TwoTier or a custom one.
+func WithBackend(backend BackendFactory) Option {
+	return func(o *options) {
+		o.backend = backend
+	}
+}
+
+// WithCodec sets the codec of the values: Gob (default), JSON, Proto, Raw or a custom one.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// Get returns the value of key, false if the key is not in the cache, has expired or is a negative entry.
+func (c *Cache[K, V]) Get(key K) (V, bool, error) {
+	en, ok, err := c.get(key)
+	return en.value, ok, err
+}
+
+// GetWithTTL returns the value of key and its remaining lifetime, false if the key is not in the cache or has expired.
+func (c *Cache[K, V]) GetWithTTL(key K) (V, time.Duration, bool, error) {
+	en, ok, err := c.get(key)
+	if !ok {
+		return en.value, 0, ok, err
+	}
+	return en.value, time.Until(en.expiry), true, nil
+}
+
+// get deletes lazily the expired entries and the ones written in another format, e.g. by another codec.
+func (c *Cache[K, V]) get(key K) (entry[K, V], bool, error) {
+	var en entry[K, V]
+	skey := toString(key)
+	b, ok := c.b.Get(skey)
+	if !ok {
+		c.m.miss()
+		return en, false, nil
+	}
+	return c.check(skey, b)
+}
+
+// check decodes the entry b of skey, counting a hit if it is a value not expired.
+func (c *Cache[K, V]) check(skey string, b []byte) (entry[K, V], bool, error) {
+	en, ok, err := c.decode(skey, b)
 	if err != nil {
-		return nil, err
+		return entry[K, V]{}, false, err
 	}
+	if !ok || en.expired(time.Now()) {
+		// the entry may have been replaced in the meantime, it is only an extra miss
+		c.b.Delete(skey)
+		c.m.miss()
+		return entry[K, V]{}, false, nil
+	}
+	if en.negative {
+		c.m.miss()
+		return entry[K, V]{}, false, nil
+	}
+	c.m.hit()
+	return en, true, nil
+}
+
+// GetMany returns the values of the keys found, in a single round trip with a backend implementing MultiGetter.
+func (c *Cache[K, V]) GetMany(keys ...K) (map[K]V, error) {
+	skeys := make([]string, len(keys))
+	for i, k := range keys {
+		skeys[i] = toString(k)
+	}
+	values := make(map[K]V, len(keys))
+	for i, b := range getMany(c.b, skeys) {
+		if b == nil {
+			c.m.miss()
+			continue
+		}
+		en, ok, err := c.check(skeys[i], b)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			values[keys[i]] = en.value
+		}
+	}
+	return values, nil
+}
 
-	return buf.Bytes(), nil
+// Set inserts or replaces the value of key, it expires after the TTL of the cache (see WithTTL).
+func (c *Cache[K, V]) Set(key K, value V) error {
+	return c.SetWithTTL(key, value, c.ttl)
 }
 
-func decode(valueBytes []byte) (interface{}, error) {
-	var value interface{}
-	buf := bytes.NewBuffer(valueBytes)
-	dec := gob.NewDecoder(buf)
+// SetWithTTL inserts or replaces the value of key, it expires after ttl.
+// ttl must not exceed the TTL of the cache (see WithTTL), after which the backend evicts the entries.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
+	if ttl <= 0 || ttl > c.ttl {
+		return fmt.Errorf("set %v: ttl must be in (0, %s], got %s", key, c.ttl, ttl)
+	}
+	c.write(toString(key))
+	return c.set(entry[K, V]{key: key, value: value, expiry: time.Now().Add(ttl)})
+}
 
-	err := dec.Decode(&value)
+func (c *Cache[K, V]) set(en entry[K, V]) error {
+	b, err := c.encode(en)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return c.b.Set(toString(en.key), b)
+}
 
-	return value, nil
+// Delete removes key, it is not an error if the key is not in the cache. A load of key in progress (see GetOrLoad)
+// does not cache its result, and the later calls of GetOrLoad do not wait for it.
+// It fails if the backend fails, e.g. a remote one, the key may then still be in the cache.
+func (c *Cache[K, V]) Delete(key K) error {
+	skey := toString(key)
+	c.write(skey)
+	if _, err := c.b.Delete(skey); err != nil {
+		return fmt.Errorf("delete %v: %w", key, err)
+	}
+	return nil
+}
+
+// Has reports whether key is in the cache and has not expired, without decoding its value.
+func (c *Cache[K, V]) Has(key K) bool {
+	b, ok := c.b.Get(toString(key))
+	if !ok {
+		return false
+	}
+	en, ok := c.header(b)
+	return ok && !en.negative && !en.expired(time.Now())
+}
+
+// Len returns the number of entries, expired entries not yet cleaned up included.
+func (c *Cache[K, V]) Len() int {
+	return c.b.Len()
+}
+
+// Reset removes all the entries, the loads in progress do not cache their results.
+func (c *Cache[K, V]) Reset() error {
+	for i := range c.writes {
+		c.writes[i].Add(1)
+	}
+	return c.b.Reset()
+}
+
+// Iterate calls fn for every entry not expired until fn returns false, in no particular order.
+// Entries set or removed during the iteration may or may not be seen.
+func (c *Cache[K, V]) Iterate(fn func(key K, value V) bool) error {
+	now := time.Now()
+	var derr error
+	err := c.b.Iterate(func(skey string, b []byte) bool {
+		en, ok, err := c.decode(skey, b)
+		if err != nil {
+			derr = fmt.Errorf("iterate %q: %w", skey, err)
+			return false
+		}
+		return !ok || en.negative || en.expired(now) || fn(en.key, en.value)
+	})
+	if derr != nil {
+		return derr
+	}
+	return err
+}
+
+func toString[K comparable](key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
 }
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a cache of the LRU backend, with a TTL of a minute unless set by opts.
+func newTestCache(t *testing.T, opts ...Option) *Cache[string, string] {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c, err := NewCache[string, string](ctx, append([]Option{WithTTL(time.Minute), WithBackend(LRU)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSetWithTTL(t *testing.T) {
+	c := newTestCache(t)
+
+	tests := []struct {
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{0, true},
+		{-time.Second, true},
+		{time.Second, false},
+		{time.Minute, false},
+		{time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ttl.String(), func(t *testing.T) {
+			err := c.SetWithTTL("key", "value", tt.ttl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			_, ttl, ok, err := c.GetWithTTL("key")
+			if err != nil || !ok || ttl <= 0 || ttl > tt.ttl {
+				t.Errorf("got ttl %s, %v, %v, want up to %s", ttl, ok, err, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		errs []string
+	}{
+		{"defaults", nil, nil},
+		{"shards", []Option{WithShards(64)}, nil},
+		{"no limit", []Option{WithMaxSizeMB(0), WithCleanWindow(0)}, nil},
+		{"shards not a power of two", []Option{WithShards(3)}, []string{"shards must be a power of two, got 3"}},
+		{"no shards", []Option{WithShards(0)}, []string{"shards must be a power of two, got 0"}},
+		{"ttl", []Option{WithTTL(0)}, []string{"ttl must be positive, got 0s"}},
+		{"clean window", []Option{WithCleanWindow(-time.Second)}, []string{"clean window must be positive or 0, got -1s"}},
+		{"max size", []Option{WithMaxSizeMB(-1)}, []string{"max size must be positive or 0, got -1MB"}},
+		{"expected entries", []Option{WithExpectedEntries(0)}, []string{"expected entries must be positive, got 0"}},
+		{"codec", []Option{WithCodec(nil)}, []string{"codec must not be nil"}},
+		{"backend", []Option{WithBackend(nil)}, []string{"backend must not be nil"}},
+		{
+			"several",
+			[]Option{WithShards(3), WithTTL(-time.Minute), WithExpectedEntries(-1)},
+			[]string{"shards must be a power of two, got 3", "ttl must be positive, got -1m0s", "expected entries must be positive, got -1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := evaluateOptions(tt.opts).validate()
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("no error")
+			}
+			want := "cache: invalid options: " + strings.Join(tt.errs, "\n")
+			if err.Error() != want {
+				t.Errorf("got %q, want %q", err, want)
+			}
+			if _, err := NewCache[string, string](context.Background(), tt.opts...); err == nil {
+				t.Error("cache created with invalid options")
+			}
+		})
+	}
+}
+
+type user struct {
+	Name string
+	Tags []string
+}
+
+func TestCache(t *testing.T) {
+	backends := []struct {
+		name    string
+		backend BackendFactory
+	}{
+		{"bigcache", BigCache},
+		{"lru", LRU},
+	}
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c, err := NewCache[int, user](ctx, WithBackend(b.backend), WithMaxSizeMB(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			alice, bob := user{"alice", []string{"admin"}}, user{Name: "bob"}
+			for k, v := range map[int]user{1: alice, 2: bob} {
+				if err := c.Set(k, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if v, ok, err := c.Get(1); err != nil || !ok || !reflect.DeepEqual(v, alice) {
+				t.Errorf("got %v, %v, %v", v, ok, err)
+			}
+			if v, ok, err := c.Get(3); err != nil || ok || !reflect.DeepEqual(v, user{}) {
+				t.Errorf("got a missing entry %v, %v, %v", v, ok, err)
+			}
+			if !c.Has(2) || c.Has(3) {
+				t.Errorf("has 2 %v, has 3 %v", c.Has(2), c.Has(3))
+			}
+			if n := c.Len(); n != 2 {
+				t.Errorf("got %d entries, want 2", n)
+			}
+
+			values, err := c.GetMany(1, 2, 3)
+			if err != nil || !reflect.DeepEqual(values, map[int]user{1: alice, 2: bob}) {
+				t.Errorf("got %v, %v", values, err)
+			}
+
+			seen := map[int]user{}
+			if err := c.Iterate(func(k int, v user) bool { seen[k] = v; return true }); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(seen, values) {
+				t.Errorf("iterated %v, want %v", seen, values)
+			}
+			n := 0
+			c.Iterate(func(int, user) bool { n++; return false })
+			if n != 1 {
+				t.Errorf("iterated %d entries after stopping, want 1", n)
+			}
+
+			if err := c.Delete(1); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Delete(1); err != nil {
+				t.Errorf("deleting a missing key: %v", err)
+			}
+			if c.Has(1) {
+				t.Error("deleted entry found")
+			}
+
+			if err := c.Reset(); err != nil {
+				t.Fatal(err)
+			}
+			if n := c.Len(); n != 0 || c.Has(2) {
+				t.Errorf("got %d entries after reset", n)
+			}
+		})
+	}
+}
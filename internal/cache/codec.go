@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec serializes the values of a Cache.
+type Codec interface {
+	// ID identifies the codec in the stored entries, so entries written by another codec are ignored.
+	// IDs below 128 are reserved for the codecs of this package.
+	ID() byte
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes data into v, a pointer to the value type of the Cache.
+	Unmarshal(data []byte, v any) error
+}
+
+const (
+	codecGob byte = iota + 1
+	codecJSON
+	codecProto
+	codecRaw
+)
+
+var (
+	// Gob is the default codec, only the exported fields of structs are stored.
+	// Interface types must be registered by the caller with gob.Register.
+	Gob Codec = gobCodec{}
+	// JSON uses encoding/json, only the exported fields of structs are stored.
+	JSON Codec = jsonCodec{}
+	// Proto uses proto.Marshal, the value type must be a generated message (e.g. *pb.SayRequest).
+	Proto Codec = protoCodec{}
+	// Raw stores the values as is, the value type must be []byte.
+	Raw Codec = rawCodec{}
+)
+
+type gobCodec struct{}
+
+func (gobCodec) ID() byte {
+	return codecGob
+}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte {
+	return codecJSON
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) ID() byte {
+	return codecProto
+}
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal allocates the message if v points to a nil message pointer.
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("proto codec: %T is not a pointer", v)
+	}
+	if e := rv.Elem(); e.Kind() == reflect.Pointer && e.IsNil() {
+		e.Set(reflect.New(e.Type().Elem()))
+	}
+	m, ok := rv.Elem().Interface().(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto codec: %T is not a proto.Message", rv.Elem().Interface())
+	}
+	return proto.Unmarshal(data, m)
+}
+
+type rawCodec struct{}
+
+func (rawCodec) ID() byte {
+	return codecRaw
+}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec: %T is not a []byte", v)
+	}
+	return b, nil
+}
+
+// Unmarshal does not copy data, the Cache gives a slice it does not reuse.
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec: %T is not a *[]byte", v)
+	}
+	*b = data
+	return nil
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-misc/internal/grpc/pb"
+)
+
+// payload is the value of the codec benchmarks, in the type expected by every codec.
+var payload = strings.Repeat("Hello, Bonjour, Salama. ", 8)
+
+type message struct {
+	Message string
+}
+
+func benchmarkCodec[V any](b *testing.B, codec Codec, v V) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := codec.Marshal(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v V
+			if err := codec.Unmarshal(data, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGob(b *testing.B) {
+	benchmarkCodec(b, Gob, message{payload})
+}
+
+func BenchmarkJSON(b *testing.B) {
+	benchmarkCodec(b, JSON, message{payload})
+}
+
+func BenchmarkProto(b *testing.B) {
+	benchmarkCodec(b, Proto, &pb.SayResponse{Message: payload})
+}
+
+func BenchmarkRaw(b *testing.B) {
+	benchmarkCodec(b, Raw, []byte(payload))
+}
+
+// sharedBackend gives the same backend to every cache.
+func sharedBackend(b Backend) BackendFactory {
+	return func(context.Context, BackendConfig) (Backend, error) {
+		return b, nil
+	}
+}
+
+func TestEntryOfAnotherFormatIsAMiss(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b, _ := LRU(ctx, BackendConfig{TTL: time.Minute})
+	jc, err := NewCache[string, message](ctx, WithBackend(sharedBackend(b)), WithCodec(JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gc, err := NewCache[string, message](ctx, WithBackend(sharedBackend(b)), WithCodec(Gob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jc.Set("codec", message{"json"}); err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := b.Get("codec")
+	old := append([]byte{}, entry...)
+	old[0] = entryVersion - 1
+	if err := b.Set("version", old); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"codec", "version"} {
+		t.Run(key, func(t *testing.T) {
+			v, ok, err := gc.Get(key)
+			if err != nil || ok {
+				t.Fatalf("got %v, %v, %v, want a miss", v, ok, err)
+			}
+			if _, ok := b.Get(key); ok {
+				t.Error("entry not removed")
+			}
+		})
+	}
+}
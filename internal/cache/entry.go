@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	e "go-misc/internal/errors"
+)
+
+// entryVersion is the version of the layout of the stored entries:
+//
+//	version (1 byte) | codec ID (1 byte) | flags (1 byte) | expiry (unix nano, 8 bytes) | load duration (ns, 8 bytes) |
+//	key length (uvarint) | key | value
+//
+// String keys are not stored (they are the BigCache keys), other keys are gob encoded.
+// A negative entry has its NotFound error (code, public message and ErrorInfo) as JSON instead of a value,
+// or nothing. Version 1 had no expiry, version 2 had no flags and load duration.
+const entryVersion byte = 3
+
+const headerLen = 1 + 1 + 1 + 8 + 8
+
+// flagNegative marks a negative entry (see WithNegativeTTL), it has no value.
+const flagNegative byte = 1 << 0
+
+// entry is a decoded stored entry.
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	expiry   time.Time
+	delta    time.Duration // duration of the load, for WithEarlyRefresh
+	negative bool
+	err      error // NotFound error of a negative entry, may be nil
+}
+
+// negativeInfo is the NotFound error of a negative entry as stored.
+type negativeInfo struct {
+	Code     string            `json:"code"`
+	Message  string            `json:"message"`
+	Reason   string            `json:"reason,omitempty"`
+	Domain   string            `json:"domain,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func newNegativeInfo(err error) negativeInfo {
+	ni := negativeInfo{Code: e.GetCode(err).String(), Message: e.CodeNotFound.Message()}
+	if terr, ok := e.Trusted(err).(interface{ Message() string }); ok {
+		ni.Message = terr.Message()
+	}
+	for _, d := range e.GetDetails(err) {
+		if ei, ok := d.(e.ErrorInfo); ok {
+			ni.Reason, ni.Domain, ni.Metadata = ei.Reason, ei.Domain, ei.Metadata
+			break
+		}
+	}
+	return ni
+}
+
+func (ni negativeInfo) error() error {
+	code, ok := e.ParseCode(ni.Code)
+	if !ok {
+		code = e.CodeNotFound
+	}
+	return e.FromErrorInfo(code, ni.Message, e.ErrorInfo{Reason: ni.Reason, Domain: ni.Domain, Metadata: ni.Metadata})
+}
+
+func (en entry[K, V]) expired(now time.Time) bool {
+	return !now.Before(en.expiry)
+}
+
+func (c *Cache[K, V]) encode(en entry[K, V]) ([]byte, error) {
+	var kb []byte
+	if _, ok := any(en.key).(string); !ok {
+		buf := bytes.Buffer{}
+		if err := gob.NewEncoder(&buf).Encode(en.key); err != nil {
+			return nil, fmt.Errorf("encode key: %w", err)
+		}
+		kb = buf.Bytes()
+	}
+	var flags byte
+	var vb []byte
+	if en.negative {
+		flags |= flagNegative
+		if en.err != nil {
+			var err error
+			if vb, err = json.Marshal(newNegativeInfo(en.err)); err != nil {
+				return nil, fmt.Errorf("encode error: %w", err)
+			}
+		}
+	} else {
+		t := time.Now()
+		var err error
+		vb, err = c.codec.Marshal(en.value)
+		c.m.observe("encode", t, err)
+		if err != nil {
+			return nil, fmt.Errorf("encode value: %w", err)
+		}
+	}
+
+	b := make([]byte, 0, headerLen+binary.MaxVarintLen64+len(kb)+len(vb))
+	b = append(b, entryVersion, c.codec.ID(), flags)
+	b = binary.BigEndian.AppendUint64(b, uint64(en.expiry.UnixNano()))
+	b = binary.BigEndian.AppendUint64(b, uint64(en.delta))
+	b = binary.AppendUvarint(b, uint64(len(kb)))
+	b = append(b, kb...)
+	return append(b, vb...), nil
+}
+
+// header decodes the header of the entry (no key nor value), false if the entry has another version or codec.
+func (c *Cache[K, V]) header(b []byte) (en entry[K, V], ok bool) {
+	if len(b) < headerLen || b[0] != entryVersion || b[1] != c.codec.ID() {
+		return en, false
+	}
+	en.negative = b[2]&flagNegative != 0
+	en.expiry = time.Unix(0, int64(binary.BigEndian.Uint64(b[3:11])))
+	en.delta = time.Duration(binary.BigEndian.Uint64(b[11:headerLen]))
+	return en, true
+}
+
+// decode returns false if the entry has another version or codec, it must be considered a miss.
+func (c *Cache[K, V]) decode(skey string, b []byte) (en entry[K, V], ok bool, err error) {
+	if en, ok = c.header(b); !ok {
+		return en, false, nil
+	}
+	b = b[headerLen:]
+	n, l := binary.Uvarint(b)
+	if l <= 0 || uint64(len(b)-l) < n {
+		return en, false, errors.New("decode: corrupted entry")
+	}
+	kb, vb := b[l:l+int(n)], b[l+int(n):]
+
+	if s, ok := any(&en.key).(*string); ok {
+		*s = skey
+	} else if err := gob.NewDecoder(bytes.NewReader(kb)).Decode(&en.key); err != nil {
+		return en, false, fmt.Errorf("decode key: %w", err)
+	}
+	if en.negative {
+		if len(vb) > 0 {
+			var ni negativeInfo
+			if err := json.Unmarshal(vb, &ni); err != nil {
+				return en, false, fmt.Errorf("decode error: %w", err)
+			}
+			en.err = ni.error()
+		}
+		return en, true, nil
+	}
+	t := time.Now()
+	err = c.codec.Unmarshal(vb, &en.value)
+	c.m.observe("decode", t, err)
+	if err != nil {
+		return en, false, fmt.Errorf("decode value: %w", err)
+	}
+	return en, true, nil
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is the removal of an entry from the cache, delivered to the listeners registered by Subscribe.
+type Event[K comparable, V any] struct {
+	Key    K
+	Value  V
+	Reason Reason
+}
+
+type subscribeOptions struct {
+	// buffer is the number of events a listener can lag behind, the next ones are dropped.
+	buffer int
+
+	// reasons filters the events by reason, all of them if empty.
+	reasons map[Reason]bool
+}
+
+type SubscribeOption func(*subscribeOptions)
+
+func evaluateSubscribeOptions(opts []SubscribeOption) *subscribeOptions {
+	opt := &subscribeOptions{
+		buffer: 1024,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// WithBuffer sets how many events a listener can lag behind (1024 by default), the next ones are dropped.
+func WithBuffer(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.buffer = n
+	}
+}
+
+// WithReasons only delivers the events of the given reasons.
+func WithReasons(reasons ...Reason) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.reasons = make(map[Reason]bool, len(reasons))
+		for _, r := range reasons {
+			o.reasons[r] = true
+		}
+	}
+}
+
+// removal is an entry removed by BigCache, not decoded yet.
+type removal struct {
+	key    string
+	entry  []byte
+	reason Reason
+}
+
+type subscription struct {
+	ch      chan removal
+	reasons map[Reason]bool
+	done    chan struct{}
+	once    sync.Once
+}
+
+// subscriptions are the listeners of a Cache, notify is called by BigCache under the lock of a shard
+// so it only queues the removals, they are decoded and delivered by the goroutine of each subscription.
+type subscriptions struct {
+	mtx  sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+func (ss *subscriptions) notify(r removal, dropped func()) {
+	ss.mtx.RLock()
+	defer ss.mtx.RUnlock()
+	for s := range ss.subs {
+		if len(s.reasons) > 0 && !s.reasons[r.reason] {
+			continue
+		}
+		select {
+		case s.ch <- r:
+		default:
+			dropped()
+		}
+	}
+}
+
+// Subscribe calls fn with the decoded key and value of every entry removed from the cache (negative entries excepted),
+// asynchronously: fn is called by a goroutine of the subscription, one event at a time, and the events are dropped
+// when fn lags behind (see WithBuffer) so BigCache is never blocked.
+// An entry found expired by a lookup is reported as Expired. Overwritten entries and Reset are not reported.
+// The subscription ends when unsubscribe is called or the context of the cache is done.
+func (c *Cache[K, V]) Subscribe(fn func(Event[K, V]), opts ...SubscribeOption) (unsubscribe func()) {
+	o := evaluateSubscribeOptions(opts)
+	s := &subscription{ch: make(chan removal, o.buffer), reasons: o.reasons, done: make(chan struct{})}
+
+	c.subs.mtx.Lock()
+	c.subs.subs[s] = struct{}{}
+	c.subs.mtx.Unlock()
+
+	unsubscribe = func() {
+		s.once.Do(func() {
+			c.subs.mtx.Lock()
+			delete(c.subs.subs, s)
+			c.subs.mtx.Unlock()
+			close(s.done)
+		})
+	}
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case r := <-s.ch:
+				en, ok, err := c.decode(r.key, r.entry)
+				if err != nil || !ok || en.negative {
+					continue
+				}
+				fn(Event[K, V]{Key: en.key, Value: en.value, Reason: r.reason})
+			case <-s.done:
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return unsubscribe
+}
+
+// onRemove is the BigCache callback, it must be fast: it runs under the lock of the shard of the entry.
+func (c *Cache[K, V]) onRemove(key string, entry []byte, reason Reason, onEvict func(string, Reason)) {
+	if reason == Deleted {
+		// lazily deleted by a lookup
+		if en, ok := c.header(entry); ok && en.expired(time.Now()) {
+			reason = Expired
+		}
+	}
+	c.m.evict(reason)
+	if onEvict != nil {
+		onEvict(key, reason)
+	}
+	c.subs.notify(removal{key: key, entry: entry, reason: reason}, c.m.drop)
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// listen subscribes to c and returns the channel of the events and the unsubscribe function.
+func listen(c *Cache[string, string], opts ...SubscribeOption) (<-chan Event[string, string], func()) {
+	events := make(chan Event[string, string], 16)
+	unsubscribe := c.Subscribe(func(ev Event[string, string]) { events <- ev }, opts...)
+	return events, unsubscribe
+}
+
+func nextEvent(t *testing.T, events <-chan Event[string, string]) Event[string, string] {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return Event[string, string]{}
+	}
+}
+
+func noEvent(t *testing.T, events <-chan Event[string, string]) {
+	t.Helper()
+	select {
+	case ev := <-events:
+		t.Errorf("got event %v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// large is a value of 400KB, the third one set in a cache of 1MB evicts the first one.
+var large = strings.Repeat("x", 400<<10)
+
+func TestSubscribe(t *testing.T) {
+	c := newTestCache(t, WithMaxSizeMB(1))
+	events, _ := listen(c)
+
+	t.Run("deleted", func(t *testing.T) {
+		c.Set("a", "1")
+		c.Delete("a")
+		if ev := nextEvent(t, events); ev != (Event[string, string]{"a", "1", Deleted}) {
+			t.Errorf("got %v", ev)
+		}
+	})
+
+	t.Run("expired by a lookup", func(t *testing.T) {
+		c.SetWithTTL("b", "2", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		if _, ok, _ := c.Get("b"); ok {
+			t.Fatal("expired entry found")
+		}
+		if ev := nextEvent(t, events); ev != (Event[string, string]{"b", "2", Expired}) {
+			t.Errorf("got %v", ev)
+		}
+	})
+
+	t.Run("no space", func(t *testing.T) {
+		for _, key := range []string{"c1", "c2", "c3"} {
+			c.Set(key, large)
+		}
+		if ev := nextEvent(t, events); ev.Key != "c1" || ev.Value != large || ev.Reason != NoSpace {
+			t.Errorf("got %s %s", ev.Key, ev.Reason)
+		}
+	})
+
+	t.Run("overwritten", func(t *testing.T) {
+		c.Set("d", "1")
+		c.Set("d", "2")
+		noEvent(t, events)
+	})
+}
+
+func TestSubscribeWithReasons(t *testing.T) {
+	c := newTestCache(t, WithMaxSizeMB(1))
+	events, _ := listen(c, WithReasons(NoSpace))
+
+	c.Set("a", "1")
+	c.Delete("a")
+	for _, key := range []string{"c1", "c2", "c3"} {
+		c.Set(key, large)
+	}
+	if ev := nextEvent(t, events); ev.Key != "c1" || ev.Reason != NoSpace {
+		t.Errorf("got %s %s", ev.Key, ev.Reason)
+	}
+	noEvent(t, events)
+}
+
+func TestSubscribeDropsEvents(t *testing.T) {
+	c := newTestCache(t, WithMetrics(WithRegisterer(prometheus.NewRegistry())))
+	block := make(chan struct{})
+	received := make(chan string, 4)
+	c.Subscribe(func(ev Event[string, string]) {
+		<-block
+		received <- ev.Key
+	}, WithBuffer(1))
+
+	// the listener blocks on the first event, the buffer holds the second one at most
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, key := range []string{"a", "b", "c", "d"} {
+			c.Set(key, "1")
+			c.Delete(key)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the cache is blocked by the listener")
+	}
+	dropped := c.m.dropped.Load()
+	if dropped < 2 {
+		t.Errorf("%d events dropped, want at least 2", dropped)
+	}
+
+	close(block)
+	n := uint64(0)
+	for n+dropped < 4 {
+		select {
+		case <-received:
+			n++
+		case <-time.After(time.Second):
+			t.Fatalf("%d events received and %d dropped, want 4 in total", n, dropped)
+		}
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := NewCache[string, string](ctx, WithBackend(LRU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions := func() int {
+		c.subs.mtx.RLock()
+		defer c.subs.mtx.RUnlock()
+		return len(c.subs.subs)
+	}
+
+	events, unsubscribe := listen(c)
+	listen(c)
+	unsubscribe()
+	unsubscribe()
+	if n := subscriptions(); n != 1 {
+		t.Fatalf("%d subscriptions, want 1", n)
+	}
+	c.Set("a", "1")
+	c.Delete("a")
+	noEvent(t, events)
+
+	// the goroutine of the other subscription unsubscribes it once the context of the cache is done
+	cancel()
+	for deadline := time.Now().Add(time.Second); subscriptions() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not ended with the context of the cache")
+		}
+	}
+}
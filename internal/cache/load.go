@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	e "go-misc/internal/errors"
+)
+
+// ErrNotFound is returned by GetOrLoad for a negative entry (see WithNegativeTTL), errors.Is matches it
+// while the error keeps the code, public message and ErrorInfo of the NotFound error of the loader.
+var ErrNotFound = e.New(e.CodeNotFound, "not found")
+
+// negativeError is the error of a negative entry, see ErrNotFound.
+type negativeError struct {
+	error
+}
+
+func (n negativeError) Unwrap() error {
+	return n.error
+}
+
+func (n negativeError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+type loadOptions struct {
+	// ttl is the TTL of the loaded values, the TTL of the cache by default.
+	ttl time.Duration
+
+	// negativeTTL is the TTL of the NotFound results of the loader, 0 disables the negative caching.
+	negativeTTL time.Duration
+
+	// stale is how long an expired value can be returned while it is reloaded in the background, 0 disables it.
+	stale time.Duration
+
+	// beta tunes the probabilistic early refresh, 0 disables it.
+	beta float64
+
+	// timeout bounds a load, the deadline of the first caller if earlier. 0 disables it.
+	timeout time.Duration
+}
+
+type LoadOption func(*loadOptions)
+
+func (c *Cache[K, V]) evaluateLoadOptions(opts []LoadOption) (*loadOptions, error) {
+	opt := &loadOptions{
+		ttl:     c.ttl,
+		timeout: 10 * time.Second,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	if err := opt.validate(c.ttl); err != nil {
+		return nil, err
+	}
+	return opt, nil
+}
+
+// validate checks that the entries stay in the backend, which evicts them after the TTL of the cache,
+// for as long as they are used.
+func (o *loadOptions) validate(ttl time.Duration) error {
+	var errs []error
+	if o.ttl < 0 || o.ttl+o.stale > ttl {
+		errs = append(errs, fmt.Errorf("load ttl (%s) plus stale window (%s) must be in [0, %s]", o.ttl, o.stale, ttl))
+	}
+	if o.stale < 0 {
+		errs = append(errs, fmt.Errorf("stale window must be positive or 0, got %s", o.stale))
+	}
+	if o.negativeTTL < 0 || o.negativeTTL > ttl {
+		errs = append(errs, fmt.Errorf("negative ttl must be in [0, %s], got %s", ttl, o.negativeTTL))
+	}
+	if o.beta < 0 {
+		errs = append(errs, fmt.Errorf("beta must be positive or 0, got %g", o.beta))
+	}
+	if o.timeout < 0 {
+		errs = append(errs, fmt.Errorf("load timeout must be positive or 0, got %s", o.timeout))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("cache: invalid load options: %w", err)
+	}
+	return nil
+}
+
+// WithLoadTTL sets the TTL of the loaded values (see SetWithTTL), the TTL of the cache by default.
+// 0 does not cache them, only the concurrent loads are collapsed.
+// With a stale window (see WithStaleWhileRevalidate), it must be set so that both fit in the TTL of the cache.
+func WithLoadTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithNegativeTTL caches the NotFound errors of the loader (see errors.GetCode) for ttl,
+// GetOrLoad returns them meanwhile, matching ErrNotFound.
+func WithNegativeTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate returns an expired value for up to d after its expiry while it is reloaded in the background.
+// The entry must still be in the backend: GetOrLoad fails if the load TTL plus d exceeds the TTL of the cache.
+func WithStaleWhileRevalidate(d time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.stale = d
+	}
+}
+
+// WithEarlyRefresh reloads a value in the background before it expires with a probability increasing as its expiry
+// gets closer, weighted by the duration of its last load, to avoid a stampede on expiry (XFetch).
+// beta = 1 is a good default, greater values refresh earlier.
+// https://cseweb.ucsd.edu/~avattani/papers/cache_stampede.pdf
+func WithEarlyRefresh(beta float64) LoadOption {
+	return func(o *loadOptions) {
+		o.beta = beta
+	}
+}
+
+// WithLoadTimeout bounds the duration of a load (10s by default, 0 disables it), the loader must honor its ctx.
+// Otherwise a hung loader would keep every later caller of the key waiting on it.
+func WithLoadTimeout(d time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.timeout = d
+	}
+}
+
+// GetOrLoad returns the value of key, calling loader on a miss and caching its result.
+// Concurrent loads of the same key are collapsed into one, the loader is called with the context
+// of the first caller without its cancellation but with its deadline (see WithLoadTimeout),
+// and every caller stops waiting when its own ctx is done.
+// The loaded value is returned even if it cannot be cached. A negative entry counts as a miss, like with Get.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context) (V, error), opts ...LoadOption) (V, error) {
+	o, err := c.evaluateLoadOptions(opts)
+	if err != nil {
+		var v V
+		return v, err
+	}
+	skey := toString(key)
+	now := time.Now()
+
+	// a corrupted entry is reloaded
+	if b, ok := c.b.Get(skey); ok {
+		if en, ok, err := c.decode(skey, b); err == nil && ok {
+			switch {
+			case !en.expired(now):
+				if o.beta > 0 && en.refreshEarly(now, o.beta) {
+					c.group.DoChan(skey, c.loadFunc(ctx, key, loader, o))
+				}
+				return c.result(en)
+			case now.Before(en.expiry.Add(o.stale)):
+				c.group.DoChan(skey, c.loadFunc(ctx, key, loader, o))
+				return c.result(en)
+			}
+		}
+	}
+	c.m.miss()
+
+	ch := c.group.DoChan(skey, c.loadFunc(ctx, key, loader, o))
+	select {
+	case <-ctx.Done():
+		var v V
+		return v, ctx.Err()
+	case r := <-ch:
+		v, _ := r.Val.(V)
+		return v, r.Err
+	}
+}
+
+// stripe returns the counter of the writes of skey, shared with other keys.
+func (c *Cache[K, V]) stripe(skey string) *atomic.Uint64 {
+	return &c.writes[maphash.String(c.seed, skey)%uint64(len(c.writes))]
+}
+
+// write records a write of skey (Set, Delete), the loads in progress must not cache their older result
+// and the next callers must not wait for them.
+func (c *Cache[K, V]) write(skey string) {
+	c.stripe(skey).Add(1)
+	c.group.Forget(skey)
+}
+
+// setLoaded caches the result of a load started at the write count gen of its key, unless the key has been written since.
+// A write racing with the set is detected afterwards, the result is then deleted.
+func (c *Cache[K, V]) setLoaded(en entry[K, V], gen uint64) {
+	skey := toString(en.key)
+	s := c.stripe(skey)
+	if s.Load() != gen {
+		return
+	}
+	c.set(en)
+	if s.Load() != gen {
+		c.b.Delete(skey)
+	}
+}
+
+// loadFunc returns the singleflight function calling loader and caching its result.
+func (c *Cache[K, V]) loadFunc(ctx context.Context, key K, loader func(ctx context.Context) (V, error), o *loadOptions) func() (any, error) {
+	deadline, hasDeadline := ctx.Deadline()
+	ctx = context.WithoutCancel(ctx)
+	return func() (any, error) {
+		gen := c.stripe(toString(key)).Load()
+		start := time.Now()
+		ctx, cancel := ctx, context.CancelFunc(func() {})
+		switch {
+		case o.timeout > 0 && (!hasDeadline || start.Add(o.timeout).Before(deadline)):
+			ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		case hasDeadline:
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+		}
+		defer cancel()
+
+		v, err := loader(ctx)
+		delta := time.Since(start)
+		switch {
+		case err == nil:
+			if o.ttl > 0 {
+				c.setLoaded(entry[K, V]{key: key, value: v, expiry: time.Now().Add(o.ttl), delta: delta}, gen)
+			}
+			return v, nil
+		case o.negativeTTL > 0 && e.GetCode(err) == e.CodeNotFound:
+			c.setLoaded(entry[K, V]{key: key, expiry: time.Now().Add(o.negativeTTL), delta: delta, negative: true, err: err}, gen)
+			return nil, negativeError{err}
+		}
+		return nil, err
+	}
+}
+
+// result counts the cached entry as a hit, or as a miss if negative, and returns its value or ErrNotFound.
+func (c *Cache[K, V]) result(en entry[K, V]) (V, error) {
+	if en.negative {
+		c.m.miss()
+		var v V
+		if en.err == nil {
+			return v, ErrNotFound
+		}
+		return v, negativeError{en.err}
+	}
+	c.m.hit()
+	return en.value, nil
+}
+
+// refreshEarly reports whether the entry must be refreshed before its expiry:
+// now - delta * beta * ln(rand) >= expiry, rand in (0, 1].
+func (en entry[K, V]) refreshEarly(now time.Time, beta float64) bool {
+	gap := -float64(en.delta) * beta * math.Log(1-rand.Float64())
+	if gap >= float64(math.MaxInt64) {
+		return true
+	}
+	return !now.Add(time.Duration(gap)).Before(en.expiry)
+}
@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	e "go-misc/internal/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newLoadCache(t *testing.T, ttl time.Duration) *Cache[string, string] {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c, err := NewCache[string, string](ctx, WithTTL(ttl), WithBackend(LRU),
+		WithMetrics(WithRegisterer(prometheus.NewRegistry())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// blockingLoader returns a loader counting its calls and returning the number of the call once release is closed.
+func blockingLoader(loads *atomic.Int32, release <-chan struct{}) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		n := loads.Add(1)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return string(rune('0' + n)), nil
+	}
+}
+
+// waitLoads waits for n calls of the loader, then lets the other callers join them.
+func waitLoads(t *testing.T, loads *atomic.Int32, n int32) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); loads.Load() < n; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d loads, want %d", loads.Load(), n)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+}
+
+func TestGetOrLoadCollapses(t *testing.T) {
+	c := newLoadCache(t, time.Minute)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := blockingLoader(&loads, release)
+
+	const n = 10
+	var wg sync.WaitGroup
+	values := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = c.GetOrLoad(context.Background(), "key", loader)
+		}(i)
+	}
+	waitLoads(t, &loads, 1)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil || values[i] != "1" {
+			t.Errorf("caller %d: got %q, %v", i, values[i], errs[i])
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("got %d loads, want 1", n)
+	}
+}
+
+func TestGetOrLoadCanceledWaiter(t *testing.T) {
+	c := newLoadCache(t, time.Minute)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := blockingLoader(&loads, release)
+
+	type result struct {
+		v   string
+		err error
+	}
+	first := make(chan result, 1)
+	go func() {
+		v, err := c.GetOrLoad(context.Background(), "key", loader)
+		first <- result{v, err}
+	}()
+	waitLoads(t, &loads, 1)
+
+	// the second caller stops waiting, the load goes on for the first one
+	ctx, cancel := context.WithCancel(context.Background())
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "key", loader)
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-second:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("canceled caller still waiting")
+	}
+
+	close(release)
+	if r := <-first; r.err != nil || r.v != "1" {
+		t.Errorf("got %q, %v", r.v, r.err)
+	}
+	if v, ok, _ := c.Get("key"); !ok || v != "1" {
+		t.Errorf("got %q, %v, want the loaded value cached", v, ok)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("got %d loads, want 1", n)
+	}
+}
+
+func TestGetOrLoadWriteDuringLoad(t *testing.T) {
+	for _, write := range []string{"delete", "set"} {
+		t.Run(write, func(t *testing.T) {
+			c := newLoadCache(t, time.Minute)
+			var loads atomic.Int32
+			release := make(chan struct{})
+			loader := blockingLoader(&loads, release)
+
+			var wg sync.WaitGroup
+			values := make([]string, 2)
+			load := func(i int) {
+				defer wg.Done()
+				values[i], _ = c.GetOrLoad(context.Background(), "key", loader)
+			}
+			wg.Add(1)
+			go load(0)
+			waitLoads(t, &loads, 1)
+
+			// the load in progress read the value before the write, a later call does not wait for it
+			want := "2"
+			if write == "delete" {
+				c.Delete("key")
+			} else {
+				want = "new"
+				c.Set("key", want)
+			}
+			wg.Add(1)
+			go load(1)
+			if write == "delete" {
+				waitLoads(t, &loads, 2)
+			}
+			close(release)
+			wg.Wait()
+
+			if values[0] != "1" {
+				t.Errorf("got %q from the first load", values[0])
+			}
+			if values[1] != want {
+				t.Errorf("got %q after the write, want %q", values[1], want)
+			}
+			if v, _, _ := c.Get("key"); v != want {
+				t.Errorf("got %q cached, want %q", v, want)
+			}
+		})
+	}
+}
+
+func TestGetOrLoadEarlyRefresh(t *testing.T) {
+	c := newLoadCache(t, time.Minute)
+	var loads atomic.Int32
+	reloaded := make(chan struct{}, 2)
+	loader := func(context.Context) (string, error) {
+		n := loads.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		reloaded <- struct{}{}
+		return string(rune('0' + n)), nil
+	}
+	// with such a beta, a load of 10ms is refreshed long before its expiry
+	opt := WithEarlyRefresh(1e9)
+
+	if v, err := c.GetOrLoad(context.Background(), "key", loader, opt); err != nil || v != "1" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	<-reloaded
+	if v, err := c.GetOrLoad(context.Background(), "key", loader, opt); err != nil || v != "1" {
+		t.Fatalf("got %q, %v, want the cached value", v, err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("not refreshed in the background")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if v, ok, _ := c.Get("key"); !ok || v != "2" {
+		t.Errorf("got %q, %v, want the refreshed value", v, ok)
+	}
+
+	// without early refresh, a cached value is not reloaded
+	c.GetOrLoad(context.Background(), "key", loader)
+	time.Sleep(20 * time.Millisecond)
+	if n := loads.Load(); n != 2 {
+		t.Errorf("got %d loads, want 2", n)
+	}
+}
+
+func TestGetOrLoadTimeout(t *testing.T) {
+	c := newLoadCache(t, time.Minute)
+	var loads atomic.Int32
+	hung := func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetOrLoad(context.Background(), "key", hung, WithLoadTimeout(20*time.Millisecond))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("got %d loads, want 2: a later call must not join a timed out load", n)
+	}
+
+	t.Run("deadline of the caller", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		want, _ := ctx.Deadline()
+		v, err := c.GetOrLoad(ctx, "deadline", func(ctx context.Context) (string, error) {
+			d, ok := ctx.Deadline()
+			if !ok || !d.Equal(want) {
+				t.Errorf("got deadline %v, want %v", d, want)
+			}
+			return "v", nil
+		})
+		if err != nil || v != "v" {
+			t.Fatalf("got %q, %v", v, err)
+		}
+	})
+}
+
+func TestGetOrLoadNegativeIsAMiss(t *testing.T) {
+	c := newLoadCache(t, time.Minute)
+	notFound := func(context.Context) (string, error) {
+		return "", e.New(e.CodeNotFound, "no")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "key", notFound, WithNegativeTTL(time.Minute)); e.GetCode(err) != e.CodeNotFound {
+			t.Fatalf("got %v, want NotFound", err)
+		}
+	}
+	if _, ok, _ := c.Get("key"); ok {
+		t.Fatal("negative entry found")
+	}
+	if hits, misses := c.m.hits.Load(), c.m.misses.Load(); hits != 0 || misses != 4 {
+		t.Errorf("got %d hits and %d misses, want 0 and 4", hits, misses)
+	}
+}
+
+func TestGetOrLoadStaleWhileRevalidate(t *testing.T) {
+	c := newLoadCache(t, 200*time.Millisecond)
+	ctx := context.Background()
+
+	if _, err := c.GetOrLoad(ctx, "key", nil, WithStaleWhileRevalidate(100*time.Millisecond)); err == nil {
+		t.Fatal("no error with the default load ttl plus a stale window exceeding the ttl of the cache")
+	}
+
+	var loads atomic.Int32
+	reloaded := make(chan struct{}, 2)
+	loader := func(context.Context) (string, error) {
+		n := loads.Add(1)
+		reloaded <- struct{}{}
+		return string(rune('0' + n)), nil
+	}
+	opts := []LoadOption{WithLoadTTL(50 * time.Millisecond), WithStaleWhileRevalidate(100 * time.Millisecond)}
+	if v, err := c.GetOrLoad(ctx, "key", loader, opts...); err != nil || v != "1" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	<-reloaded
+
+	time.Sleep(80 * time.Millisecond)
+	if v, err := c.GetOrLoad(ctx, "key", loader, opts...); err != nil || v != "1" {
+		t.Fatalf("got %q, %v, want the stale value", v, err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded in the background")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if v, err := c.GetOrLoad(ctx, "key", loader, opts...); err != nil || v != "2" {
+		t.Fatalf("got %q, %v, want the reloaded value", v, err)
+	}
+}
@@ -0,0 +1,376 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process backend evicting the least recently used entries when MaxSizeMB is reached,
+// every entry is accounted with the size of its key and value. Shards is ignored, there is a single lock.
+func LRU(ctx context.Context, cfg BackendConfig) (Backend, error) {
+	return newLRU(ctx, cfg, false), nil
+}
+
+// TinyLFU is LRU with the W-TinyLFU admission policy: new entries go through a small LRU window (1% of the size),
+// then only replace the least recently used entry of the main segmented LRU if they are used more frequently.
+// It has better hit ratios than LRU and BigCache on skewed workloads, the frequencies are estimated by a count-min
+// sketch sized by ExpectedEntries. It is LRU if MaxSizeMB is 0.
+// https://arxiv.org/abs/1512.00727
+func TinyLFU(ctx context.Context, cfg BackendConfig) (Backend, error) {
+	return newLRU(ctx, cfg, cfg.MaxSizeMB > 0), nil
+}
+
+// entryOverhead approximates the memory used by an entry besides its key and value (item, list element, map slot).
+const entryOverhead = 96
+
+// segments of the W-TinyLFU, LRU only uses window
+type segment int
+
+const (
+	window segment = iota
+	probation
+	protected
+)
+
+type lruItem struct {
+	key    string
+	entry  []byte
+	size   int
+	expiry time.Time
+	seg    segment
+}
+
+type lru struct {
+	mtx   sync.Mutex
+	items map[string]*list.Element
+	lists [protected + 1]*list.List // by segment, most recently used first
+	sizes [protected + 1]int        // in bytes, by segment
+	stats Stats
+
+	ttl      time.Duration
+	onRemove func(key string, entry []byte, reason Reason)
+
+	// limits in bytes, 0 means no limit
+	maxSize      int // whole cache
+	maxWindow    int
+	maxMain      int // probation and protected
+	maxProtected int
+
+	sketch *sketch // nil for LRU
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newLRU(ctx context.Context, cfg BackendConfig, tinyLFU bool) *lru {
+	l := &lru{
+		items:    make(map[string]*list.Element),
+		ttl:      cfg.TTL,
+		onRemove: cfg.OnRemove,
+		maxSize:  cfg.MaxSizeMB << 20,
+		closed:   make(chan struct{}),
+	}
+	for i := range l.lists {
+		l.lists[i] = list.New()
+	}
+	l.maxWindow = l.maxSize
+	if tinyLFU {
+		l.maxWindow = l.maxSize / 100
+		l.maxMain = l.maxSize - l.maxWindow
+		l.maxProtected = l.maxMain * 8 / 10
+		l.sketch = newSketch(cfg.ExpectedEntries)
+	}
+
+	if cfg.CleanWindow > 0 {
+		go func() {
+			t := time.NewTicker(cfg.CleanWindow)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					l.cleanUp()
+				case <-ctx.Done():
+					return
+				case <-l.closed:
+					return
+				}
+			}
+		}()
+	}
+	return l
+}
+
+func (l *lru) Get(key string) ([]byte, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.sketch != nil {
+		l.sketch.increment(key)
+	}
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	it := el.Value.(*lruItem)
+	if !time.Now().Before(it.expiry) {
+		l.remove(el, Expired)
+		return nil, false
+	}
+	l.touch(el)
+	return bytes.Clone(it.entry), true
+}
+
+func (l *lru) Set(key string, entry []byte) error {
+	size := len(key) + len(entry) + entryOverhead
+	limit := l.maxSize
+	if l.sketch != nil {
+		limit = l.maxMain
+	}
+	if limit > 0 && size > limit {
+		return errTooLarge
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.sketch != nil {
+		l.sketch.increment(key)
+	}
+	expiry := time.Now().Add(l.ttl)
+	if el, ok := l.items[key]; ok {
+		it := el.Value.(*lruItem)
+		l.sizes[it.seg] += size - it.size
+		it.entry, it.size, it.expiry = entry, size, expiry
+		l.touch(el)
+	} else {
+		it := &lruItem{key: key, entry: entry, size: size, expiry: expiry, seg: window}
+		l.items[key] = l.lists[window].PushFront(it)
+		l.sizes[window] += size
+	}
+	l.evict()
+	return nil
+}
+
+func (l *lru) Delete(key string) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		l.stats.DeleteMisses++
+		return false, nil
+	}
+	l.stats.DeleteHits++
+	l.remove(el, Deleted)
+	return true, nil
+}
+
+// Iterate copies the entries before calling fn, so fn can use the backend.
+func (l *lru) Iterate(fn func(key string, entry []byte) bool) error {
+	type kv struct {
+		key   string
+		entry []byte
+	}
+	l.mtx.Lock()
+	now := time.Now()
+	kvs := make([]kv, 0, len(l.items))
+	for _, el := range l.items {
+		if it := el.Value.(*lruItem); now.Before(it.expiry) {
+			kvs = append(kvs, kv{it.key, it.entry})
+		}
+	}
+	l.mtx.Unlock()
+
+	for _, kv := range kvs {
+		if !fn(kv.key, bytes.Clone(kv.entry)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lru) Len() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return len(l.items)
+}
+
+func (l *lru) Capacity() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.sizes[window] + l.sizes[probation] + l.sizes[protected]
+}
+
+func (l *lru) Stats() Stats {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.stats
+}
+
+func (l *lru) Reset() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.items = make(map[string]*list.Element)
+	for i := range l.lists {
+		l.lists[i].Init()
+		l.sizes[i] = 0
+	}
+	if l.sketch != nil {
+		l.sketch.reset()
+	}
+	return nil
+}
+
+func (l *lru) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+// touch records an access to el: an entry of probation is promoted to protected,
+// which demotes the least recently used entries of protected to probation if it is full.
+func (l *lru) touch(el *list.Element) {
+	it := el.Value.(*lruItem)
+	if it.seg != probation {
+		l.lists[it.seg].MoveToFront(el)
+		return
+	}
+
+	l.move(el, protected)
+	for l.sizes[protected] > l.maxProtected {
+		l.move(l.lists[protected].Back(), probation)
+	}
+}
+
+// move moves el to the front of seg.
+func (l *lru) move(el *list.Element, seg segment) {
+	it := el.Value.(*lruItem)
+	l.lists[it.seg].Remove(el)
+	l.sizes[it.seg] -= it.size
+	it.seg = seg
+	l.items[it.key] = l.lists[seg].PushFront(it)
+	l.sizes[seg] += it.size
+}
+
+// evict enforces the limits. With TinyLFU, the entries leaving the window are admitted in probation
+// if there is room or if they are more frequent than the least recently used entries of the main segments.
+func (l *lru) evict() {
+	if l.sketch == nil {
+		for l.maxSize > 0 && l.sizes[window] > l.maxSize {
+			l.remove(l.lists[window].Back(), NoSpace)
+		}
+		return
+	}
+
+	for l.sizes[window] > l.maxWindow {
+		candidate := l.lists[window].Back()
+		it := candidate.Value.(*lruItem)
+		for l.sizes[probation]+l.sizes[protected]+it.size > l.maxMain {
+			victim := l.victim()
+			if l.sketch.estimate(it.key) <= l.sketch.estimate(victim.Value.(*lruItem).key) {
+				l.remove(candidate, NoSpace)
+				candidate = nil
+				break
+			}
+			l.remove(victim, NoSpace)
+		}
+		if candidate != nil {
+			l.move(candidate, probation)
+		}
+	}
+	// an updated entry may have grown
+	for l.sizes[probation]+l.sizes[protected] > l.maxMain {
+		l.remove(l.victim(), NoSpace)
+	}
+}
+
+// victim returns the least recently used entry of probation, or of protected if probation is empty.
+func (l *lru) victim() *list.Element {
+	if el := l.lists[probation].Back(); el != nil {
+		return el
+	}
+	return l.lists[protected].Back()
+}
+
+func (l *lru) remove(el *list.Element, reason Reason) {
+	it := el.Value.(*lruItem)
+	l.lists[it.seg].Remove(el)
+	l.sizes[it.seg] -= it.size
+	delete(l.items, it.key)
+	if l.onRemove != nil {
+		l.onRemove(it.key, it.entry, reason)
+	}
+}
+
+func (l *lru) cleanUp() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := time.Now()
+	for _, el := range l.items {
+		if !now.Before(el.Value.(*lruItem).expiry) {
+			l.remove(el, Expired)
+		}
+	}
+}
+
+// sketch is a count-min sketch of 4 rows of 4-bit saturating counters (stored in bytes),
+// halved every 10 accesses per counter of a row so that the frequencies age.
+type sketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	seed      maphash.Seed
+	additions int
+	sample    int
+}
+
+func newSketch(expectedEntries int) *sketch {
+	width := 16
+	for width < expectedEntries && width < 1<<24 {
+		width <<= 1
+	}
+	s := &sketch{mask: uint64(width - 1), seed: maphash.MakeSeed(), sample: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index returns the counter of key in row i (double hashing).
+func (s *sketch) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & s.mask
+}
+
+func (s *sketch) increment(key string) {
+	h := maphash.String(s.seed, key)
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < 15 {
+			*c++
+		}
+	}
+	if s.additions++; s.additions >= s.sample {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *sketch) estimate(key string) uint8 {
+	h := maphash.String(s.seed, key)
+	est := uint8(15)
+	for i := range s.rows {
+		est = min(est, s.rows[i][s.index(h, i)])
+	}
+	return est
+}
+
+func (s *sketch) reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.additions = 0
+}
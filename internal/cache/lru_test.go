@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// itemSize is the accounted size of the test entries: a 1 byte key and a 4 bytes entry.
+const itemSize = 1 + 4 + entryOverhead
+
+// newTestTinyLFU returns a TinyLFU holding 1 entry in its window, 3 in its main segments, 2 of them in protected.
+func newTestTinyLFU(t *testing.T) (*lru, *[]string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	var removed []string
+	l := newLRU(ctx, BackendConfig{TTL: time.Minute, MaxSizeMB: 1, ExpectedEntries: 1024, OnRemove: func(key string, _ []byte, reason Reason) {
+		removed = append(removed, key+":"+reason.String())
+	}}, true)
+	l.maxSize, l.maxWindow, l.maxMain, l.maxProtected = 4*itemSize, itemSize, 3*itemSize, 2*itemSize
+	return l, &removed
+}
+
+func (l *lru) segmentOf(key string) string {
+	el, ok := l.items[key]
+	if !ok {
+		return "none"
+	}
+	return [...]string{"window", "probation", "protected"}[el.Value.(*lruItem).seg]
+}
+
+func (l *lru) mustSet(t *testing.T, key string) {
+	t.Helper()
+	if err := l.Set(key, []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkSegments(t *testing.T, l *lru, want map[string]string) {
+	t.Helper()
+	for key, seg := range want {
+		if got := l.segmentOf(key); got != seg {
+			t.Errorf("%s in %s, want %s", key, got, seg)
+		}
+	}
+}
+
+func TestTinyLFUSegments(t *testing.T) {
+	l, removed := newTestTinyLFU(t)
+
+	// new entries go through the window, then to probation while there is room
+	l.mustSet(t, "a")
+	checkSegments(t, l, map[string]string{"a": "window"})
+	l.mustSet(t, "b")
+	l.mustSet(t, "c")
+	checkSegments(t, l, map[string]string{"a": "probation", "b": "probation", "c": "window"})
+
+	// an access in probation promotes to protected, the least recently used of protected is demoted when full
+	l.Get("a")
+	l.Get("b")
+	checkSegments(t, l, map[string]string{"a": "protected", "b": "protected"})
+	l.mustSet(t, "d")
+	l.Get("c")
+	checkSegments(t, l, map[string]string{"a": "probation", "b": "protected", "c": "protected", "d": "window"})
+	if len(*removed) != 0 {
+		t.Errorf("removed %v, want none", *removed)
+	}
+}
+
+func TestTinyLFUAdmission(t *testing.T) {
+	l, removed := newTestTinyLFU(t)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		l.mustSet(t, key)
+	}
+	// main is full (a, b, c), d is in the window
+	for i := 0; i < 3; i++ {
+		l.Get("a")
+		l.Get("b")
+		l.Get("c")
+	}
+
+	// e pushes d out of the window, d is less frequent than the victim so it is rejected
+	l.mustSet(t, "e")
+	checkSegments(t, l, map[string]string{"d": "none", "e": "window"})
+	if got := strings.Join(*removed, ","); got != "d:no_space" {
+		t.Errorf("removed %s, want d:no_space", got)
+	}
+
+	// f is more frequent than the victim, the least recently used of probation, which it replaces
+	for i := 0; i < 10; i++ {
+		l.Get("f")
+	}
+	l.mustSet(t, "f")
+	victim := l.lists[probation].Back().Value.(*lruItem).key
+	l.mustSet(t, "g")
+	checkSegments(t, l, map[string]string{"e": "none", "f": "probation", "g": "window", victim: "none"})
+	if got, want := strings.Join(*removed, ","), "d:no_space,e:no_space,"+victim+":no_space"; got != want {
+		t.Errorf("removed %s, want %s", got, want)
+	}
+}
+
+func TestTinyLFUGrowingUpdate(t *testing.T) {
+	l, removed := newTestTinyLFU(t)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		l.mustSet(t, key)
+	}
+	l.Get("a")
+	l.Get("a")
+
+	// a grows in protected beyond the size of main, the least recently used entries are evicted
+	if err := l.Set("a", make([]byte, 4+2*itemSize)); err != nil {
+		t.Fatal(err)
+	}
+	checkSegments(t, l, map[string]string{"a": "protected", "b": "none", "c": "none", "d": "window"})
+	if got := strings.Join(*removed, ","); got != "b:no_space,c:no_space" {
+		t.Errorf("removed %s, want b:no_space,c:no_space", got)
+	}
+	if total := l.sizes[probation] + l.sizes[protected]; total > l.maxMain {
+		t.Errorf("main is %d bytes, more than %d", total, l.maxMain)
+	}
+}
+
+func TestLRUCapacity(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b, _ := LRU(ctx, BackendConfig{TTL: time.Minute, MaxSizeMB: 1})
+
+	b.Set("a", make([]byte, 10))
+	b.Set("bb", make([]byte, 20))
+	if got, want := b.Capacity(), 1+10+2+20+2*entryOverhead; got != want {
+		t.Errorf("capacity %d, want %d", got, want)
+	}
+	b.Set("a", make([]byte, 30))
+	if got, want := b.Capacity(), 1+30+2+20+2*entryOverhead; got != want {
+		t.Errorf("capacity after update %d, want %d", got, want)
+	}
+	b.Delete("bb")
+	if got, want := b.Capacity(), 1+30+entryOverhead; got != want {
+		t.Errorf("capacity after delete %d, want %d", got, want)
+	}
+	if err := b.Set("big", make([]byte, 1<<20)); err != errTooLarge {
+		t.Errorf("got %v, want %v", err, errTooLarge)
+	}
+}
+
+// traceEnv is the path of a recorded trace replayed by TestHitRatio and BenchmarkHitRatio, one key per line.
+const traceEnv = "CACHE_TRACE"
+
+// loadTrace returns the recorded trace if any, otherwise a synthetic one: Zipf distributed keys
+// interrupted by scans of keys used once, which defeat LRU.
+func loadTrace(tb testing.TB) []string {
+	tb.Helper()
+	if path := os.Getenv(traceEnv); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		defer f.Close()
+		var keys []string
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			keys = append(keys, s.Text())
+		}
+		if err := s.Err(); err != nil {
+			tb.Fatal(err)
+		}
+		return keys
+	}
+
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 100000)
+	const n = 200000
+	keys := make([]string, 0, n)
+	for scan := 0; len(keys) < n; scan++ {
+		for i := 0; i < 5000; i++ {
+			keys = append(keys, fmt.Sprint("z", zipf.Uint64()))
+		}
+		for i := 0; i < 1000; i++ {
+			keys = append(keys, fmt.Sprint("s", scan, "-", i))
+		}
+	}
+	return keys
+}
+
+// hitRatio replays keys on a backend of 1MB holding entries of 1KB, an entry is set on every miss.
+func hitRatio(tb testing.TB, factory BackendFactory, keys []string) float64 {
+	tb.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b, err := factory(ctx, BackendConfig{Shards: 16, TTL: time.Hour, MaxSizeMB: 1, ExpectedEntries: 1000})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer b.Close()
+
+	entry := make([]byte, 1024)
+	hits := 0
+	for _, key := range keys {
+		if _, ok := b.Get(key); ok {
+			hits++
+			continue
+		}
+		if err := b.Set(key, entry); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return float64(hits) / float64(len(keys))
+}
+
+var backends = []struct {
+	name    string
+	factory BackendFactory
+}{
+	{"BigCache", BigCache},
+	{"LRU", LRU},
+	{"TinyLFU", TinyLFU},
+}
+
+func TestHitRatio(t *testing.T) {
+	keys := loadTrace(t)
+	ratios := make(map[string]float64, len(backends))
+	for _, b := range backends {
+		ratios[b.name] = hitRatio(t, b.factory, keys)
+		t.Logf("%s: %.3f", b.name, ratios[b.name])
+	}
+	if os.Getenv(traceEnv) == "" && !(ratios["TinyLFU"] > ratios["LRU"] && ratios["TinyLFU"] > ratios["BigCache"]) {
+		t.Errorf("TinyLFU does not have the best hit ratio on the synthetic trace: %v", ratios)
+	}
+}
+
+func BenchmarkHitRatio(b *testing.B) {
+	keys := loadTrace(b)
+	for _, be := range backends {
+		b.Run(be.name, func(b *testing.B) {
+			var ratio float64
+			for i := 0; i < b.N; i++ {
+				ratio = hitRatio(b, be.factory, keys)
+			}
+			b.ReportMetric(100*ratio, "hit%")
+		})
+	}
+}
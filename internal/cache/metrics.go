@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metricsOptions struct {
+	reg       prometheus.Registerer
+	namespace string
+	buckets   []float64 // codec duration buckets, in seconds
+}
+
+type MetricsOption func(*metricsOptions)
+
+func evaluateMetricsOptions(opts []MetricsOption) *metricsOptions {
+	opt := &metricsOptions{
+		reg:       prometheus.DefaultRegisterer,
+		namespace: "",
+		buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10), // 1µs to 262ms
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// metrics of a Cache, the methods are no-ops on a nil *metrics (metrics disabled).
+type metrics struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions [Deleted + 1]atomic.Uint64 // by Reason
+	dropped   atomic.Uint64              // events, see Subscribe
+
+	codecErrors   *prometheus.CounterVec
+	codecDuration *prometheus.HistogramVec
+
+	o      *metricsOptions
+	labels prometheus.Labels
+}
+
+func (m *metrics) hit() {
+	if m != nil {
+		m.hits.Add(1)
+	}
+}
+
+func (m *metrics) miss() {
+	if m != nil {
+		m.misses.Add(1)
+	}
+}
+
+func (m *metrics) evict(r Reason) {
+	if m != nil && r > 0 && int(r) < len(m.evictions) {
+		m.evictions[r].Add(1)
+	}
+}
+
+func (m *metrics) drop() {
+	if m != nil {
+		m.dropped.Add(1)
+	}
+}
+
+// observe records the duration and the error of a codec operation ("encode" or "decode") started at t.
+func (m *metrics) observe(op string, t time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.codecDuration.WithLabelValues(op).Observe(time.Since(t).Seconds())
+	if err != nil {
+		m.codecErrors.WithLabelValues(op).Inc()
+	}
+}
+
+func newMetrics(name string, opts []MetricsOption) *metrics {
+	o := evaluateMetricsOptions(opts)
+	labels := prometheus.Labels{"cache": name}
+	return &metrics{
+		o:      o,
+		labels: labels,
+		codecErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "cache",
+			Name:        "codec_errors_total",
+			Help:        "Total number of values that could not be encoded or decoded.",
+			ConstLabels: labels,
+		}, []string{"op"}),
+		codecDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "cache",
+			Name:        "codec_seconds",
+			Help:        "Duration of the encoding and decoding of the values.",
+			Buckets:     o.buckets,
+			ConstLabels: labels,
+		}, []string{"op"}),
+	}
+}
+
+// collector exports the metrics and the Backend stats of a Cache, labelled by the name of the cache.
+type collector struct {
+	m *metrics
+	b Backend
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	collisions *prometheus.Desc
+	delHits    *prometheus.Desc
+	delMisses  *prometheus.Desc
+	entries    *prometheus.Desc
+	capacity   *prometheus.Desc
+	evictions  *prometheus.Desc
+	dropped    *prometheus.Desc
+}
+
+// register registers the collector of m and b, it fails if a cache of the same name is registered.
+func (m *metrics) register(b Backend) error {
+	labels := m.labels
+	fqName := func(name string) string {
+		return prometheus.BuildFQName(m.o.namespace, "cache", name)
+	}
+	c := &collector{
+		m: m,
+		b: b,
+
+		hits:       prometheus.NewDesc(fqName("hits_total"), "Total number of lookups finding a live entry.", nil, labels),
+		misses:     prometheus.NewDesc(fqName("misses_total"), "Total number of lookups finding no live entry.", nil, labels),
+		collisions: prometheus.NewDesc(fqName("collisions_total"), "Total number of key hash collisions.", nil, labels),
+		delHits:    prometheus.NewDesc(fqName("delete_hits_total"), "Total number of deletes of an existing key.", nil, labels),
+		delMisses:  prometheus.NewDesc(fqName("delete_misses_total"), "Total number of deletes of a missing key.", nil, labels),
+		entries:    prometheus.NewDesc(fqName("entries"), "Number of entries, expired ones not yet removed included.", nil, labels),
+		capacity:   prometheus.NewDesc(fqName("capacity_bytes"), "Bytes allocated for the entries.", nil, labels),
+		evictions:  prometheus.NewDesc(fqName("evictions_total"), "Total number of removed entries by reason.", []string{"reason"}, labels),
+		dropped:    prometheus.NewDesc(fqName("events_dropped_total"), "Total number of removal events dropped because a listener lagged behind.", nil, labels),
+	}
+	return m.o.reg.Register(c)
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.collisions
+	ch <- c.delHits
+	ch <- c.delMisses
+	ch <- c.entries
+	ch <- c.capacity
+	ch <- c.evictions
+	ch <- c.dropped
+	c.m.codecErrors.Describe(ch)
+	c.m.codecDuration.Describe(ch)
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.b.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(c.m.hits.Load()))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(c.m.misses.Load()))
+	ch <- prometheus.MustNewConstMetric(c.collisions, prometheus.CounterValue, float64(s.Collisions))
+	ch <- prometheus.MustNewConstMetric(c.delHits, prometheus.CounterValue, float64(s.DeleteHits))
+	ch <- prometheus.MustNewConstMetric(c.delMisses, prometheus.CounterValue, float64(s.DeleteMisses))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(c.b.Len()))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(c.b.Capacity()))
+	for _, r := range []Reason{Expired, NoSpace, Deleted} {
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(c.m.evictions[r].Load()), r.String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(c.m.dropped.Load()))
+	c.m.codecErrors.Collect(ch)
+	c.m.codecDuration.Collect(ch)
+}
+
+// WithRegisterer is a functional option to register the collector somewhere else than
+// prometheus.DefaultRegisterer, e.g. a private prometheus.NewRegistry() in tests.
+func WithRegisterer(reg prometheus.Registerer) MetricsOption {
+	return func(o *metricsOptions) {
+		o.reg = reg
+	}
+}
+
+func WithNamespace(namespace string) MetricsOption {
+	return func(o *metricsOptions) {
+		o.namespace = namespace
+	}
+}
+
+func WithBuckets(buckets []float64) MetricsOption {
+	return func(o *metricsOptions) {
+		o.buckets = buckets
+	}
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	e "go-misc/internal/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := newTestCache(t, WithName("test"), WithMaxSizeMB(1), WithMetrics(WithRegisterer(reg), WithNamespace("ns")))
+	ctx := context.Background()
+
+	c.Set("a", "1")
+	c.Get("a")
+	c.Get("missing")
+	notFound := func(context.Context) (string, error) { return "", e.New(e.CodeNotFound, "no") }
+	for i := 0; i < 2; i++ {
+		c.GetOrLoad(ctx, "negative", notFound, WithNegativeTTL(time.Minute))
+	}
+
+	c.Delete("a")
+	c.SetWithTTL("b", "2", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	c.Get("b")
+	// an entry of the codec of the cache which cannot be decoded, it is an error rather than a miss
+	corrupted := binary.BigEndian.AppendUint64([]byte{entryVersion, c.codec.ID(), 0}, uint64(time.Now().Add(time.Minute).UnixNano()))
+	c.b.Set("corrupted", append(corrupted, make([]byte, headerLen)...))
+	if _, _, err := c.Get("corrupted"); err == nil {
+		t.Error("no decode error")
+	}
+	c.Delete("corrupted")
+	// the negative entry and c1 are evicted
+	for _, key := range []string{"c1", "c2", "c3"} {
+		c.Set(key, large)
+	}
+
+	want := `
+# HELP ns_cache_hits_total Total number of lookups finding a live entry.
+# TYPE ns_cache_hits_total counter
+ns_cache_hits_total{cache="test"} 1
+# HELP ns_cache_misses_total Total number of lookups finding no live entry.
+# TYPE ns_cache_misses_total counter
+ns_cache_misses_total{cache="test"} 4
+# HELP ns_cache_entries Number of entries, expired ones not yet removed included.
+# TYPE ns_cache_entries gauge
+ns_cache_entries{cache="test"} 2
+# HELP ns_cache_evictions_total Total number of removed entries by reason.
+# TYPE ns_cache_evictions_total counter
+ns_cache_evictions_total{cache="test",reason="deleted"} 2
+ns_cache_evictions_total{cache="test",reason="expired"} 1
+ns_cache_evictions_total{cache="test",reason="no_space"} 2
+# HELP ns_cache_codec_errors_total Total number of values that could not be encoded or decoded.
+# TYPE ns_cache_codec_errors_total counter
+ns_cache_codec_errors_total{cache="test",op="decode"} 1
+`
+	names := []string{"ns_cache_hits_total", "ns_cache_misses_total", "ns_cache_entries", "ns_cache_evictions_total", "ns_cache_codec_errors_total"}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), names...); err != nil {
+		t.Error(err)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		switch mf.GetName() {
+		case "ns_cache_capacity_bytes":
+			if got, want := mf.GetMetric()[0].GetGauge().GetValue(), float64(c.b.Capacity()); got != want || got == 0 {
+				t.Errorf("capacity %g, want %g", got, want)
+			}
+		case "ns_cache_codec_seconds":
+			for _, m := range mf.GetMetric() {
+				if m.GetHistogram().GetSampleCount() == 0 {
+					t.Errorf("no codec duration observed for %v", m.GetLabel())
+				}
+			}
+			if n := len(mf.GetMetric()); n != 2 {
+				t.Errorf("got codec durations for %d ops, want encode and decode", n)
+			}
+		}
+	}
+	if problems, err := testutil.GatherAndLint(reg); err != nil || len(problems) > 0 {
+		t.Errorf("lint: %v, %v", problems, err)
+	}
+}
+
+func TestMetricsEncodeError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := NewCache[string, func()](ctx, WithBackend(LRU), WithMetrics(WithRegisterer(reg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("f", func() {}); err == nil {
+		t.Fatal("no error")
+	}
+
+	want := `
+# HELP cache_codec_errors_total Total number of values that could not be encoded or decoded.
+# TYPE cache_codec_errors_total counter
+cache_codec_errors_total{cache="default",op="encode"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "cache_codec_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetricsDuplicateName(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i, name := range []string{"a", "b", "a"} {
+		_, err := NewCache[string, string](ctx, WithName(name), WithBackend(LRU), WithMetrics(WithRegisterer(reg)))
+		if (err != nil) != (i == 2) {
+			t.Errorf("cache %d (%s): got %v", i, name, err)
+		}
+	}
+}
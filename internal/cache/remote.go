@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-misc/internal/resp"
+)
+
+// errUnsupported is returned by the remote backend for the operations spanning the whole server.
+var errUnsupported = errors.New("not supported by the remote backend")
+
+// Remote is a backend storing the entries in a Redis compatible server through client, so they are shared by
+// the replicas of a service. Keys are prefixed by prefix, which should be distinct for every cache on the server.
+// Entries are set with the TTL of the cache (EX, rounded up to the second), the server evicts them.
+//
+// The server is never scanned nor flushed: Iterate and Reset return an error, Len, Capacity and Stats are zero,
+// and OnRemove is never called. A failed Get is a miss logged at warn level with the Logger of the config if any,
+// a failed Set or Delete returns the error. The client is closed with the backend, once the context of the cache is done.
+func Remote(client *resp.Client, prefix string) BackendFactory {
+	return func(ctx context.Context, cfg BackendConfig) (Backend, error) {
+		return &remote{c: client, prefix: prefix, ttl: cfg.TTL, l: cfg.Logger}, nil
+	}
+}
+
+type remote struct {
+	c      *resp.Client
+	prefix string
+	ttl    time.Duration
+	l      *slog.Logger // may be nil
+}
+
+func (r *remote) Get(key string) ([]byte, bool) {
+	entry, ok, err := r.c.Get(context.Background(), r.prefix+key)
+	if err != nil {
+		r.warn("get", key, err)
+		return nil, false
+	}
+	return entry, ok
+}
+
+// GetMany gets the entries with a single MGET.
+func (r *remote) GetMany(keys []string) [][]byte {
+	pkeys := make([]string, len(keys))
+	for i, k := range keys {
+		pkeys[i] = r.prefix + k
+	}
+	entries, err := r.c.MGet(context.Background(), pkeys...)
+	if err != nil {
+		r.warn("mget", "", err)
+		return make([][]byte, len(keys))
+	}
+	return entries
+}
+
+func (r *remote) Set(key string, entry []byte) error {
+	return r.c.Set(context.Background(), r.prefix+key, entry, r.ttl)
+}
+
+func (r *remote) Delete(key string) (bool, error) {
+	n, err := r.c.Del(context.Background(), r.prefix+key)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *remote) Iterate(func(key string, entry []byte) bool) error {
+	return errUnsupported
+}
+
+func (r *remote) Len() int {
+	return 0
+}
+
+func (r *remote) Capacity() int {
+	return 0
+}
+
+func (r *remote) Stats() Stats {
+	return Stats{}
+}
+
+func (r *remote) Reset() error {
+	return errUnsupported
+}
+
+func (r *remote) Close() error {
+	return r.c.Close()
+}
+
+func (r *remote) warn(op, key string, err error) {
+	if r.l != nil {
+		r.l.Warn("remote cache "+op+" failed", slog.String("key", key), slog.Any("err", err))
+	}
+}
+
+// TwoTier composes a local backend, e.g. TinyLFU, in front of a shared remote one (see Remote).
+// Get reads the local backend first, then the remote one, back-filling the local backend with the remote hits.
+// Set and Delete write through both, a failed remote Set or Delete is returned after the local one succeeded.
+//
+// Other replicas keep serving their local copy of an entry replaced or deleted elsewhere until it expires,
+// so the local backend gets the TTL localTTL if shorter than the one of the cache (0 keeps it).
+// Iterate, Len, Capacity, Stats, Reset and the removals given to OnRemove are those of the local backend:
+// snapshots, metrics and events only cover the local tier.
+func TwoTier(local, remote BackendFactory, localTTL time.Duration) BackendFactory {
+	return func(ctx context.Context, cfg BackendConfig) (Backend, error) {
+		rcfg := cfg
+		rcfg.OnRemove = nil
+		r, err := remote(ctx, rcfg)
+		if err != nil {
+			return nil, err
+		}
+
+		lcfg := cfg
+		if localTTL > 0 {
+			lcfg.TTL = min(localTTL, cfg.TTL)
+		}
+		l, err := local(ctx, lcfg)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return &twoTier{Backend: l, remote: r}, nil
+	}
+}
+
+// twoTier embeds the local backend for the operations not involving the remote one.
+type twoTier struct {
+	Backend
+	remote Backend
+}
+
+func (t *twoTier) Get(key string) ([]byte, bool) {
+	if entry, ok := t.Backend.Get(key); ok {
+		return entry, true
+	}
+	entry, ok := t.remote.Get(key)
+	if !ok {
+		return nil, false
+	}
+	// best effort, e.g. the entry may be larger than the local backend
+	_ = t.Backend.Set(key, entry)
+	return entry, true
+}
+
+// GetMany gets the local misses from the remote backend in a single round trip if it is a MultiGetter.
+func (t *twoTier) GetMany(keys []string) [][]byte {
+	entries := getMany(t.Backend, keys)
+	var missing []string
+	for i, entry := range entries {
+		if entry == nil {
+			missing = append(missing, keys[i])
+		}
+	}
+	if len(missing) == 0 {
+		return entries
+	}
+
+	remotes := getMany(t.remote, missing)
+	j := 0
+	for i := range entries {
+		if entries[i] != nil {
+			continue
+		}
+		if entry := remotes[j]; entry != nil {
+			entries[i] = entry
+			_ = t.Backend.Set(keys[i], entry)
+		}
+		j++
+	}
+	return entries
+}
+
+func (t *twoTier) Set(key string, entry []byte) error {
+	if err := t.Backend.Set(key, entry); err != nil {
+		return err
+	}
+	return t.remote.Set(key, entry)
+}
+
+func (t *twoTier) Delete(key string) (bool, error) {
+	ok, err := t.Backend.Delete(key)
+	rok, rerr := t.remote.Delete(key)
+	return ok || rok, errors.Join(err, rerr)
+}
+
+func (t *twoTier) Close() error {
+	return errors.Join(t.Backend.Close(), t.remote.Close())
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-misc/internal/resp"
+)
+
+func newTestServer(t *testing.T) (*resp.Server, *resp.Client) {
+	t.Helper()
+	srv, err := resp.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	client := resp.NewClient(srv.Addr())
+	t.Cleanup(func() { client.Close() })
+	return srv, client
+}
+
+func newBackend(t *testing.T, factory BackendFactory, cfg BackendConfig) Backend {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	b, err := factory(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func checkEntries(t *testing.T, got [][]byte, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if (got[i] == nil) != (want[i] == "") || string(got[i]) != want[i] {
+			t.Errorf("entry %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	srv, client := newTestServer(t)
+	var logs bytes.Buffer
+	b := newBackend(t, Remote(client, "p:"), BackendConfig{TTL: time.Second, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	t.Run("get", func(t *testing.T) {
+		if err := b.Set("a", []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+		if entry, ok := b.Get("a"); !ok || string(entry) != "1" {
+			t.Errorf("got %q, %v", entry, ok)
+		}
+		if _, ok, _ := client.Get(context.Background(), "p:a"); !ok {
+			t.Error("key not prefixed")
+		}
+		if _, ok := b.Get("b"); ok {
+			t.Error("got a missing entry")
+		}
+	})
+
+	t.Run("set expires", func(t *testing.T) {
+		if err := b.Set("ex", []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(1100 * time.Millisecond)
+		if _, ok := b.Get("ex"); ok {
+			t.Error("entry not expired after the ttl")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		b.Set("del", []byte("1"))
+		if ok, err := b.Delete("del"); !ok || err != nil {
+			t.Errorf("entry not deleted: %v", err)
+		}
+		if ok, err := b.Delete("del"); ok || err != nil {
+			t.Errorf("missing entry deleted: %v", err)
+		}
+	})
+
+	t.Run("get many", func(t *testing.T) {
+		b.Set("m1", []byte("1"))
+		b.Set("m3", []byte("3"))
+		calls := srv.Calls("MGET")
+		checkEntries(t, b.(MultiGetter).GetMany([]string{"m1", "m2", "m3"}), "1", "", "3")
+		if n := srv.Calls("MGET") - calls; n != 1 {
+			t.Errorf("got %d MGET, want 1", n)
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		b.Set("err", []byte("1"))
+		srv.Close()
+		if _, ok := b.Get("err"); ok {
+			t.Error("got an entry")
+		}
+		checkEntries(t, b.(MultiGetter).GetMany([]string{"err"}), "")
+		if _, err := b.Delete("err"); err == nil {
+			t.Error("no delete error")
+		}
+		if b.Set("err", []byte("1")) == nil {
+			t.Error("no set error")
+		}
+		for _, op := range []string{"get", "mget"} {
+			if !strings.Contains(logs.String(), "remote cache "+op+" failed") {
+				t.Errorf("%s failure not logged", op)
+			}
+		}
+	})
+}
+
+func TestTwoTier(t *testing.T) {
+	srv, client := newTestServer(t)
+	cfg := BackendConfig{TTL: time.Minute, MaxSizeMB: 1}
+	// r writes to the remote tier only, as another replica would
+	r := newBackend(t, Remote(client, "p:"), cfg)
+	b := newBackend(t, TwoTier(LRU, Remote(client, "p:"), 50*time.Millisecond), cfg)
+	local := b.(*twoTier).Backend
+
+	t.Run("back-fill", func(t *testing.T) {
+		r.Set("a", []byte("1"))
+		gets := srv.Calls("GET")
+		for i := 0; i < 2; i++ {
+			if entry, ok := b.Get("a"); !ok || string(entry) != "1" {
+				t.Fatalf("got %q, %v", entry, ok)
+			}
+		}
+		if n := srv.Calls("GET") - gets; n != 1 {
+			t.Errorf("got %d GET, want 1: the remote hit is not back-filled", n)
+		}
+		if _, ok := local.Get("a"); !ok {
+			t.Error("entry not in the local tier")
+		}
+	})
+
+	t.Run("get many", func(t *testing.T) {
+		b.Set("m1", []byte("1"))
+		r.Set("m2", []byte("2"))
+		r.Set("m3", []byte("3"))
+		gets, mgets := srv.Calls("GET"), srv.Calls("MGET")
+		checkEntries(t, b.(MultiGetter).GetMany([]string{"m1", "m2", "m3", "m4"}), "1", "2", "3", "")
+		if n, m := srv.Calls("GET")-gets, srv.Calls("MGET")-mgets; n != 0 || m != 1 {
+			t.Errorf("got %d GET and %d MGET, want a single MGET", n, m)
+		}
+		checkEntries(t, getMany(local, []string{"m2", "m3"}), "2", "3")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		b.Set("del", []byte("1"))
+		if ok, err := b.Delete("del"); !ok || err != nil {
+			t.Errorf("entry not deleted: %v", err)
+		}
+		if _, ok := r.Get("del"); ok {
+			t.Error("entry not deleted from the remote tier")
+		}
+	})
+
+	t.Run("local ttl", func(t *testing.T) {
+		if err := b.Set("ttl", []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if _, ok := local.Get("ttl"); ok {
+			t.Error("local entry not expired after the local ttl")
+		}
+		if _, ok := b.Get("ttl"); !ok {
+			t.Error("remote entry expired with the local ttl")
+		}
+	})
+}
+
+func TestRemoteSnapshot(t *testing.T) {
+	_, client := newTestServer(t)
+	for _, opt := range []Option{WithSnapshotFile("cache"), WithRestoreFile("cache")} {
+		_, err := NewCache[string, string](context.Background(), WithBackend(Remote(client, "p:")), opt)
+		if err == nil || !strings.Contains(err.Error(), "not supported by the remote backend") {
+			t.Errorf("got %v, want an invalid options error", err)
+		}
+	}
+	// a two tier backend snapshots its local tier
+	ctx, cancel := context.WithCancel(context.Background())
+	_, client = newTestServer(t)
+	path := filepath.Join(t.TempDir(), "cache")
+	c, err := NewCache[string, string](ctx, WithBackend(TwoTier(LRU, Remote(client, "p:"), 0)), WithSnapshotFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	<-c.Done()
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCacheClosesBackend(t *testing.T) {
+	_, client := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := NewCache[string, string](ctx, WithBackend(Remote(client, "p:")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	<-c.Done()
+	if _, _, err := client.Get(context.Background(), "p:a"); !errors.Is(err, resp.ErrClosed) {
+		t.Errorf("got %v, want %v", err, resp.ErrClosed)
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is the version of the layout of the snapshots:
+//
+//	header: magic "GMCS" | snapshot version (1 byte) | entry version (1 byte) | codec ID (1 byte) | CRC-32C (4 bytes)
+//	record: 1 | key length (uvarint) | key | entry length (uvarint) | entry | CRC-32C of key and entry (4 bytes)
+//	end:    0 | number of records (8 bytes)
+//
+// The entries are stored as is (see entryVersion), with their absolute expiry so their remaining TTL is preserved.
+const snapshotVersion byte = 1
+
+var snapshotMagic = []byte("GMCS")
+
+// maxRecordLen bounds the allocations when reading a corrupted snapshot.
+const maxRecordLen = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Snapshot writes the live entries to w, in a single pass without blocking the cache.
+// Entries set or removed during the snapshot may or may not be written.
+func (c *Cache[K, V]) Snapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	hdr := append(append([]byte{}, snapshotMagic...), snapshotVersion, entryVersion, c.codec.ID())
+	hdr = binary.BigEndian.AppendUint32(hdr, crc32.Checksum(hdr, crcTable))
+	if _, err := bw.Write(hdr); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	now := time.Now()
+	var n uint64
+	var b []byte
+	var werr error
+	err := c.b.Iterate(func(key string, entry []byte) bool {
+		if en, ok := c.header(entry); !ok || en.expired(now) {
+			return true
+		}
+
+		b = append(b[:0], 1)
+		b = binary.AppendUvarint(b, uint64(len(key)))
+		b = append(b, key...)
+		b = binary.AppendUvarint(b, uint64(len(entry)))
+		b = append(b, entry...)
+		crc := crc32.Update(crc32.Checksum([]byte(key), crcTable), crcTable, entry)
+		b = binary.BigEndian.AppendUint32(b, crc)
+		if _, werr = bw.Write(b); werr != nil {
+			return false
+		}
+		n++
+		return true
+	})
+	if err == nil {
+		err = werr
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	b = binary.BigEndian.AppendUint64(append(b[:0], 0), n)
+	if _, err := bw.Write(b); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore sets the entries of a snapshot written by Snapshot, the expired ones are skipped.
+// The snapshot must have been written by a cache with the same codec.
+// Every record is checked before being set, so a truncated or corrupted snapshot is restored up to the first bad record.
+func (c *Cache[K, V]) Restore(r io.Reader) error {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(snapshotMagic)+3+4)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return fmt.Errorf("restore: header: %w", unexpected(err))
+	}
+	if string(hdr[:4]) != string(snapshotMagic) || binary.BigEndian.Uint32(hdr[7:]) != crc32.Checksum(hdr[:7], crcTable) {
+		return errors.New("restore: not a cache snapshot")
+	}
+	if v := hdr[4]; v != snapshotVersion {
+		return fmt.Errorf("restore: unsupported snapshot version %d", v)
+	}
+	if v, id := hdr[5], hdr[6]; v != entryVersion || id != c.codec.ID() {
+		return fmt.Errorf("restore: entries of version %d and codec %d, want version %d and codec %d", v, id, entryVersion, c.codec.ID())
+	}
+
+	now := time.Now()
+	var n uint64
+	for {
+		flag, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("restore: record %d: %w", n, unexpected(err))
+		}
+		if flag == 0 {
+			break
+		}
+		key, err := readRecordField(br)
+		if err != nil {
+			return fmt.Errorf("restore: record %d: key: %w", n, err)
+		}
+		entry, err := readRecordField(br)
+		if err != nil {
+			return fmt.Errorf("restore: record %d: entry: %w", n, err)
+		}
+		var crc [4]byte
+		if _, err := io.ReadFull(br, crc[:]); err != nil {
+			return fmt.Errorf("restore: record %d: %w", n, unexpected(err))
+		}
+		if binary.BigEndian.Uint32(crc[:]) != crc32.Update(crc32.Checksum(key, crcTable), crcTable, entry) {
+			return fmt.Errorf("restore: record %d: checksum mismatch", n)
+		}
+		n++
+
+		if en, ok := c.header(entry); !ok || en.expired(now) {
+			continue
+		}
+		if err := c.b.Set(string(key), entry); err != nil {
+			return fmt.Errorf("restore: record %d: %w", n-1, err)
+		}
+	}
+
+	var count [8]byte
+	if _, err := io.ReadFull(br, count[:]); err != nil {
+		return fmt.Errorf("restore: end: %w", unexpected(err))
+	}
+	if m := binary.BigEndian.Uint64(count[:]); m != n {
+		return fmt.Errorf("restore: %d records read, %d written", n, m)
+	}
+	return nil
+}
+
+func readRecordField(br *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if l > maxRecordLen {
+		return nil, fmt.Errorf("length %d too large", l)
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, unexpected(err)
+	}
+	return b, nil
+}
+
+// unexpected turns io.EOF into io.ErrUnexpectedEOF, the end of a snapshot is explicit.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// snapshotFile writes a snapshot to path atomically, through a temporary file renamed once complete.
+func (c *Cache[K, V]) snapshotFile(path string) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err := c.Snapshot(f); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
+
+// restoreFile restores the snapshot at path, a missing file is not an error.
+func (c *Cache[K, V]) restoreFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	defer f.Close()
+	return c.Restore(f)
+}
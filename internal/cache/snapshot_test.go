@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSnapshot returns a snapshot of a cache of n entries "k0"... with the values "v0"...
+func newSnapshot(t *testing.T, n int, opts ...Option) []byte {
+	t.Helper()
+	c := newTestCache(t, opts...)
+	for i := 0; i < n; i++ {
+		if err := c.Set("k"+string(rune('0'+i)), "v"+string(rune('0'+i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	c := newTestCache(t)
+	c.Set("a", "1")
+	c.SetWithTTL("b", "2", 30*time.Second)
+	c.SetWithTTL("expired", "3", time.Millisecond)
+	_, ttl, _, _ := c.GetWithTTL("b")
+	time.Sleep(5 * time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.Len(), len(snapshotMagic)+3+4+2*(1+1+1+1+headerLen+4)+1+8; got < want {
+		t.Fatalf("snapshot of %d bytes, less than %d", got, want)
+	}
+
+	r := newTestCache(t)
+	if err := r.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.Len(); n != 2 {
+		t.Errorf("%d entries restored, want 2: the expired one is skipped", n)
+	}
+	if v, ok, err := r.Get("a"); err != nil || !ok || v != "1" {
+		t.Errorf("got %q, %v, %v", v, ok, err)
+	}
+	v, rttl, ok, err := r.GetWithTTL("b")
+	if err != nil || !ok || v != "2" {
+		t.Errorf("got %q, %v, %v", v, ok, err)
+	}
+	if rttl > ttl || rttl < ttl-time.Second {
+		t.Errorf("remaining ttl %s, want about %s", rttl, ttl)
+	}
+}
+
+func TestRestoreBadSnapshot(t *testing.T) {
+	const n = 3
+	snap := newSnapshot(t, n)
+	// offset of the snapshot version, length of the end
+	const version, end = 4, 9
+	edit := func(f func(b []byte) []byte) []byte {
+		return f(bytes.Clone(snap))
+	}
+
+	tests := []struct {
+		name     string
+		snapshot []byte
+		err      string
+		restored int
+	}{
+		{"empty", nil, "header: unexpected EOF", 0},
+		{"magic", edit(func(b []byte) []byte { b[0] = 'X'; return b }), "not a cache snapshot", 0},
+		{"header checksum", edit(func(b []byte) []byte { b[version]++; return b }), "not a cache snapshot", 0},
+		{"codec", newSnapshot(t, n, WithCodec(JSON)), "codec", 0},
+		{"record checksum", edit(func(b []byte) []byte { b[len(b)-end-5] ^= 0xff; return b }), "record 2: checksum mismatch", n - 1},
+		{"end count", edit(func(b []byte) []byte { b[len(b)-1]++; return b }), "3 records read, 4 written", n},
+		{"truncated record", edit(func(b []byte) []byte { return b[:len(b)-end-3] }), "record 2: unexpected EOF", n - 1},
+		{"truncated end", edit(func(b []byte) []byte { return b[:len(b)-4] }), "end: unexpected EOF", n},
+		{"no end", edit(func(b []byte) []byte { return b[:len(b)-end] }), "record 3: unexpected EOF", n},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t)
+			err := c.Restore(bytes.NewReader(tt.snapshot))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+			if got := c.Len(); got != tt.restored {
+				t.Errorf("%d entries restored, want %d", got, tt.restored)
+			}
+		})
+	}
+}
+
+// failingIterate is a backend failing to iterate.
+type failingIterate struct {
+	Backend
+}
+
+func (failingIterate) Iterate(func(key string, entry []byte) bool) error {
+	return errors.New("iterate failed")
+}
+
+func TestSnapshotFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache")
+
+	c := newTestCache(t)
+	if err := c.restoreFile(path); err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	c.Set("a", "1")
+	if err := c.snapshotFile(path); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// a failed snapshot leaves the previous one and no temporary file
+	f := newTestCache(t, WithBackend(func(ctx context.Context, cfg BackendConfig) (Backend, error) {
+		b, err := LRU(ctx, cfg)
+		return failingIterate{b}, err
+	}))
+	if err := f.snapshotFile(path); err == nil {
+		t.Error("no error")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Error("previous snapshot overwritten")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d files, want the snapshot only", len(entries))
+	}
+
+	r := newTestCache(t)
+	if err := r.restoreFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := r.Get("a"); !ok || v != "1" {
+		t.Errorf("got %q, %v", v, ok)
+	}
+	if err := r.snapshotFile(filepath.Join(dir, "missing", "cache")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,172 @@
+package errors
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/status"
+)
+
+// Item is a child error of a Batch, identified by its index or field key.
+type Item struct {
+	Key string
+	Err error
+}
+
+// Index creates an Item keyed by its index in the batch.
+func Index(i int, err error) Item {
+	return Item{Key: strconv.Itoa(i), Err: err}
+}
+
+// Field creates an Item keyed by a field name.
+func Field(name string, err error) Item {
+	return Item{Key: name, Err: err}
+}
+
+func (i Item) Code() Code {
+	return GetCode(i.Err)
+}
+
+// Message returns the public message of the item (see Error.Message).
+func (i Item) Message() string {
+	return publicMessage(i.Code(), i.Err)
+}
+
+// Policy computes the overall code of a Batch from the codes of its items (never empty).
+type Policy func(codes []Code) Code
+
+// severity ranks the codes for MostSevere, server errors first.
+var severity = map[Code]int{
+	CodeDataLoss:           15,
+	CodeInternal:           14,
+	CodeUnknown:            13,
+	CodeUnavailable:        12,
+	CodeDeadlineExceeded:   11,
+	CodeUnimplemented:      10,
+	CodeResourceExhausted:  9,
+	CodeAborted:            8,
+	CodeFailedPrecondition: 7,
+	CodeAlreadyExists:      6,
+	CodePermissionDenied:   5,
+	CodeUnauthenticated:    4,
+	CodeOutOfRange:         3,
+	CodeInvalidArgument:    2,
+	CodeNotFound:           1,
+	CodeCanceled:           0,
+}
+
+// MostSevere is the default Policy, it gives the most severe code (see severity).
+func MostSevere(codes []Code) Code {
+	code := codes[0]
+	for _, c := range codes[1:] {
+		if severity[c] > severity[code] {
+			code = c
+		}
+	}
+	return code
+}
+
+// First gives the code of the first item.
+func First(codes []Code) Code {
+	return codes[0]
+}
+
+// Explicit always gives code.
+func Explicit(code Code) Policy {
+	return func([]Code) Code {
+		return code
+	}
+}
+
+// Batch is a trusted aggregate of errors keeping the code of every item, for batch endpoints.
+type Batch struct {
+	items []Item
+	code  Code
+}
+
+// NewBatch creates a Batch from the non nil items, its code is computed by policy (MostSevere if nil).
+// Returns nil if there is no error.
+func NewBatch(policy Policy, items ...Item) error {
+	b := &Batch{items: make([]Item, 0, len(items))}
+	codes := make([]Code, 0, len(items))
+	for _, i := range items {
+		if i.Err != nil {
+			b.items = append(b.items, i)
+			codes = append(codes, i.Code())
+		}
+	}
+	if len(b.items) == 0 {
+		return nil
+	}
+	if policy == nil {
+		policy = MostSevere
+	}
+	b.code = policy(codes)
+	return b
+}
+
+func (b *Batch) Error() string {
+	s := make([]string, 0, len(b.items))
+	for _, i := range b.items {
+		s = append(s, i.Key+": "+i.Err.Error())
+	}
+	return strings.Join(s, ", ")
+}
+
+func (b *Batch) Unwrap() []error {
+	errs := make([]error, 0, len(b.items))
+	for _, i := range b.items {
+		errs = append(errs, i.Err)
+	}
+	return errs
+}
+
+func (b *Batch) Items() []Item {
+	return b.items
+}
+
+func (b *Batch) Code() Code {
+	return b.code
+}
+
+// Message returns the public messages of the items prefixed by their key.
+func (b *Batch) Message() string {
+	s := make([]string, 0, len(b.items))
+	for _, i := range b.items {
+		s = append(s, i.Key+": "+i.Message())
+	}
+	return strings.Join(s, ", ")
+}
+
+// batchItemReason is the reason of the ErrorInfo of an item without one, see GRPCStatus.
+const batchItemReason = "BATCH_ITEM"
+
+// localize localizes every item (see Localize), the code of the batch is kept.
+func (b *Batch) localize(locales []string) *Batch {
+	lb := &Batch{items: make([]Item, 0, len(b.items)), code: b.code}
+	for _, i := range b.items {
+		lb.items = append(lb.items, Item{Key: i.Key, Err: Localize(i.Err, locales...)})
+	}
+	return lb
+}
+
+// GRPCStatus sends every item as an ErrorInfo detail with the "key", "code" and "message" metadata,
+// the reason is the one of the catalog error of the item or "BATCH_ITEM". FromGRPCStatus rebuilds the Batch.
+func (b *Batch) GRPCStatus() *status.Status {
+	details := make([]Detail, 0, len(b.items))
+	for _, i := range b.items {
+		info := ErrorInfo{Reason: batchItemReason, Metadata: map[string]string{
+			"key":     i.Key,
+			"code":    i.Code().String(),
+			"message": i.Message(),
+		}}
+		for _, d := range GetDetails(i.Err) {
+			if ei, ok := d.(ErrorInfo); ok {
+				info.Reason, info.Domain = ei.Reason, ei.Domain
+				break
+			}
+		}
+		details = append(details, info)
+	}
+	return withProtoDetails(status.New(GrpcCode(b.code), b.Message()), details)
+}
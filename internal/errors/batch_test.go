@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errTestNotFound = testCatalog.Register("TEST_NOT_FOUND", CodeNotFound, map[string]string{
+	"en": "{id} not found",
+	"fr": "{id} introuvable",
+})
+
+func TestBatchFromGRPCStatus(t *testing.T) {
+	b := NewBatch(nil,
+		Index(0, New(CodeAborted, "conflict")),
+		Index(1, nil),
+		Index(2, errTestNotFound.New("id", "2")),
+	)
+
+	err := FromGRPCStatus(b.(*Batch).GRPCStatus())
+	rb, ok := err.(*Batch)
+	if !ok {
+		t.Fatalf("got %T, want *Batch", err)
+	}
+	if rb.Code() != CodeAborted {
+		t.Errorf("got code %v, want %v", rb.Code(), CodeAborted)
+	}
+	want := []struct {
+		key    string
+		code   Code
+		msg    string
+		reason string
+	}{
+		{"0", CodeAborted, "conflict", ""},
+		{"2", CodeNotFound, "2 not found", "TEST_NOT_FOUND"},
+	}
+	if len(rb.Items()) != len(want) {
+		t.Fatalf("got %d items, want %d", len(rb.Items()), len(want))
+	}
+	for i, item := range rb.Items() {
+		w := want[i]
+		if item.Key != w.key || item.Code() != w.code || item.Message() != w.msg {
+			t.Errorf("item %d: got %s %v %q, want %s %v %q", i, item.Key, item.Code(), item.Message(), w.key, w.code, w.msg)
+		}
+		reason := ""
+		for _, d := range GetDetails(item.Err) {
+			if ei, ok := d.(ErrorInfo); ok {
+				reason = ei.Reason
+			}
+		}
+		if reason != w.reason {
+			t.Errorf("item %d: got reason %q, want %q", i, reason, w.reason)
+		}
+	}
+}
+
+func TestLocalizeBatch(t *testing.T) {
+	b := NewBatch(First,
+		Field("a", errTestNotFound.New("id", "a")),
+		Field("b", New(CodeInvalidArgument, "bad")),
+	)
+
+	lb, ok := Localize(b, "fr-FR").(*Batch)
+	if !ok {
+		t.Fatalf("got %T, want *Batch", lb)
+	}
+	if lb.Code() != CodeNotFound {
+		t.Errorf("got code %v, want %v", lb.Code(), CodeNotFound)
+	}
+	if got, want := lb.Message(), "a: a introuvable, b: bad"; got != want {
+		t.Errorf("got message %q, want %q", got, want)
+	}
+	if got := lb.Items()[0].Code(); got != CodeNotFound {
+		t.Errorf("got item code %v, want %v", got, CodeNotFound)
+	}
+}
+
+func TestBatchDetails(t *testing.T) {
+	item := WithDetails(New(CodeInvalidArgument, "bad"), RetryInfo{Delay: time.Second})
+	b := NewBatch(nil, Index(0, item), Index(1, New(CodeNotFound, "missing")))
+
+	for _, err := range []error{b, fmt.Errorf("save: %w", b), Wrap(CodeInvalidArgument, b)} {
+		if details := GetDetails(err); len(details) != 0 {
+			t.Errorf("%v: got the details %v of an item", err, details)
+		}
+		if _, ok := RetryDelay(err); ok {
+			t.Errorf("%v: got the retry delay of an item", err)
+		}
+		if IsRetryable(err) {
+			t.Errorf("%v: retryable like an item", err)
+		}
+	}
+	if _, ok := RetryDelay(b.(*Batch).Items()[0].Err); !ok {
+		t.Error("no retry delay for the item")
+	}
+}
@@ -0,0 +1,220 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale used when none of the caller's locales has a message.
+const DefaultLocale = "en"
+
+// Catalog declares the errors of a domain once, with a stable reason and message templates per locale.
+type Catalog struct {
+	domain  string
+	mtx     sync.RWMutex
+	entries map[string]*Entry
+}
+
+// catalogs are the catalogs by domain, see FromErrorInfo.
+var catalogs sync.Map
+
+func NewCatalog(domain string) *Catalog {
+	c := &Catalog{domain: domain, entries: make(map[string]*Entry)}
+	catalogs.Store(domain, c)
+	return c
+}
+
+// FromErrorInfo rebuilds an Error from its code, public message and ErrorInfo, e.g. after caching them:
+// the catalog error of the reason if its domain has a catalog, so it can be localized,
+// otherwise an Error with msg and the ErrorInfo detail.
+func FromErrorInfo(code Code, msg string, info ErrorInfo) error {
+	if c, ok := catalogs.Load(info.Domain); ok {
+		if en, ok := c.(*Catalog).Lookup(info.Reason); ok && en.code == code {
+			return en.error(errors.New(msg), msg, info.Metadata, callers())
+		}
+	}
+	ierr := &Error{code: code, err: errors.New(msg), msg: msg, stack: callers()}
+	if info.Reason != "" {
+		ierr.details = []Detail{info}
+	}
+	return ierr
+}
+
+// Entry is an error declared in a Catalog.
+type Entry struct {
+	domain   string
+	reason   string
+	code     Code
+	messages map[string]string
+}
+
+// Register declares an error with a stable reason (e.g. "MESSAGE_NOT_FOUND") and its messages per locale,
+// placeholders like {id} are replaced by the args given to New/Wrap.
+// The DefaultLocale message is required, it panics if missing or if reason is already registered.
+func (c *Catalog) Register(reason string, code Code, messages map[string]string) *Entry {
+	if _, ok := messages[DefaultLocale]; !ok {
+		panic(fmt.Sprintf("errors: %s: no %q message", reason, DefaultLocale))
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.entries[reason]; ok {
+		panic(fmt.Sprintf("errors: %s already registered", reason))
+	}
+
+	// https://github.com/uber-go/guide/blob/master/style.md#copy-slices-and-maps-at-boundaries
+	en := &Entry{domain: c.domain, reason: reason, code: code, messages: make(map[string]string, len(messages))}
+	for k, v := range messages {
+		en.messages[strings.ToLower(k)] = v
+	}
+	c.entries[reason] = en
+	return en
+}
+
+// Lookup returns the entry registered for reason.
+func (c *Catalog) Lookup(reason string) (*Entry, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	en, ok := c.entries[reason]
+	return en, ok
+}
+
+func (en *Entry) Reason() string {
+	return en.reason
+}
+
+func (en *Entry) Code() Code {
+	return en.code
+}
+
+// New creates an Error from the entry, args are key/value pairs for the placeholders of the messages.
+// It carries an ErrorInfo detail with the reason, the domain and the args as metadata.
+func (en *Entry) New(args ...string) error {
+	a := toArgs(args)
+	msg := en.message(DefaultLocale, a)
+	return en.error(errors.New(msg), msg, a, callers())
+}
+
+// Wrap creates an Error from the entry caused by err, err is only logged.
+func (en *Entry) Wrap(err error, args ...string) error {
+	a := toArgs(args)
+	msg := en.message(DefaultLocale, a)
+	if err == nil {
+		err = errors.New(msg)
+	}
+	return en.error(err, msg, a, callers())
+}
+
+// Is reports whether err was created from the entry.
+func (en *Entry) Is(err error) bool {
+	ierr, ok := Trusted(err).(*Error)
+	for ok {
+		if ierr.entry == en {
+			return true
+		}
+		ierr, ok = Trusted(ierr.err).(*Error)
+	}
+	return false
+}
+
+func (en *Entry) error(err error, msg string, args map[string]string, stack []uintptr) error {
+	return &Error{
+		code:    en.code,
+		err:     err,
+		msg:     msg,
+		details: []Detail{ErrorInfo{Reason: en.reason, Domain: en.domain, Metadata: args}},
+		stack:   stack,
+		entry:   en,
+		args:    args,
+	}
+}
+
+func (en *Entry) message(locale string, args map[string]string) string {
+	msg := en.messages[locale]
+	for k, v := range args {
+		msg = strings.ReplaceAll(msg, "{"+k+"}", v)
+	}
+	return msg
+}
+
+// match returns the first of the locales having a message, trying the base language ("fr" for "fr-FR") as well.
+func (en *Entry) match(locales []string) (string, bool) {
+	for _, l := range locales {
+		l = strings.ToLower(l)
+		if _, ok := en.messages[l]; ok {
+			return l, true
+		}
+		if base, _, ok := strings.Cut(l, "-"); ok {
+			if _, ok := en.messages[base]; ok {
+				return base, true
+			}
+		}
+	}
+	return "", false
+}
+
+func toArgs(kv []string) map[string]string {
+	if len(kv) == 0 {
+		return nil
+	}
+	args := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		args[kv[i]] = kv[i+1]
+	}
+	return args
+}
+
+// Localize renders the public message of the catalog error of err in the first of the locales it has,
+// with a LocalizedMessage detail. err is returned as is if it is not a catalog error or no locale matches.
+// The items of a Batch are localized one by one.
+func Localize(err error, locales ...string) error {
+	if len(locales) == 0 {
+		return err
+	}
+	if b, ok := Trusted(err).(*Batch); ok {
+		return b.localize(locales)
+	}
+	ierr, ok := Trusted(err).(*Error)
+	for ok && ierr.entry == nil {
+		ierr, ok = Trusted(ierr.err).(*Error)
+	}
+	if !ok {
+		return err
+	}
+	locale, ok := ierr.entry.match(locales)
+	if !ok || locale == DefaultLocale {
+		return err
+	}
+	msg := ierr.entry.message(locale, ierr.args)
+	return &Error{code: GetCode(err), err: err, msg: msg, details: []Detail{LocalizedMessage{Locale: locale, Message: msg}}}
+}
+
+type localeKey struct{}
+
+// ContextWithLocales returns a copy of ctx carrying the caller's locales, by order of preference.
+func ContextWithLocales(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locales)
+}
+
+// LocalesFromContext returns the caller's locales set by ContextWithLocales.
+func LocalesFromContext(ctx context.Context) []string {
+	locales, _ := ctx.Value(localeKey{}).([]string)
+	return locales
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header by order of preference.
+func ParseAcceptLanguage(s string) []string {
+	tags, _, err := language.ParseAcceptLanguage(s)
+	if err != nil {
+		return nil
+	}
+	locales := make([]string, 0, len(tags))
+	for _, t := range tags {
+		locales = append(locales, t.String())
+	}
+	return locales
+}
@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testCatalog = NewCatalog("test")
+
+var errTestMessage = testCatalog.Register("TEST_MESSAGE_NOT_FOUND", CodeNotFound, map[string]string{
+	"en": "message {id} not found",
+	"fr": "message {id} introuvable",
+	"mg": "tsy hita ny hafatra {id}",
+})
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		locales []string
+		want    string
+		locale  string // of the LocalizedMessage detail, none if empty
+	}{
+		{"no locale", nil, "message 1 not found", ""},
+		{"en", []string{"en"}, "message 1 not found", ""},
+		{"fr", []string{"fr"}, "message 1 introuvable", "fr"},
+		{"mg", []string{"mg"}, "tsy hita ny hafatra 1", "mg"},
+		{"region", []string{"fr-CA"}, "message 1 introuvable", "fr"},
+		{"case", []string{"MG"}, "tsy hita ny hafatra 1", "mg"},
+		{"unknown", []string{"de"}, "message 1 not found", ""},
+		{"fallback", []string{"de-DE", "mg", "fr"}, "tsy hita ny hafatra 1", "mg"},
+		{"preference", []string{"en-US", "fr"}, "message 1 not found", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Localize(errTestMessage.Wrap(New(CodeInternal, "db: no rows"), "id", "1"), tt.locales...)
+			ierr := Trusted(err).(*Error)
+			if got := ierr.Message(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if got := GetCode(err); got != CodeNotFound {
+				t.Errorf("got code %v, want %v", got, CodeNotFound)
+			}
+			if !errTestMessage.Is(err) {
+				t.Error("localized error not from the entry")
+			}
+			locale := ""
+			for _, d := range ierr.Details() {
+				if lm, ok := d.(LocalizedMessage); ok {
+					locale = lm.Locale
+					if lm.Message != tt.want {
+						t.Errorf("got localized message %q, want %q", lm.Message, tt.want)
+					}
+				}
+			}
+			if locale != tt.locale {
+				t.Errorf("got locale %q, want %q", locale, tt.locale)
+			}
+		})
+	}
+
+	t.Run("not a catalog error", func(t *testing.T) {
+		err := New(CodeNotFound, "no row")
+		if got := Localize(err, "fr"); got != err {
+			t.Errorf("got %v, want the error as is", got)
+		}
+	})
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"fr", []string{"fr"}},
+		{"fr-FR,fr;q=0.9,en;q=0.8", []string{"fr-FR", "fr", "en"}},
+		{"en;q=0.5, mg", []string{"mg", "en"}},
+		{"", []string{}},
+		{"fr;q=x", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogLookup(t *testing.T) {
+	if en, ok := testCatalog.Lookup("TEST_MESSAGE_NOT_FOUND"); !ok || en != errTestMessage {
+		t.Errorf("got %v, %v", en, ok)
+	}
+	if _, ok := testCatalog.Lookup("MISSING"); ok {
+		t.Error("got a missing entry")
+	}
+
+	// an ErrorInfo of a missing entry gives an Error with its message, which is not localized
+	info := ErrorInfo{Reason: "MISSING", Domain: "test"}
+	err := FromErrorInfo(CodeNotFound, "gone", info)
+	if got := Trusted(Localize(err, "fr")).(*Error).Message(); got != "gone" {
+		t.Errorf("got %q, want %q", got, "gone")
+	}
+	if details := GetDetails(err); !reflect.DeepEqual(details, []Detail{info}) {
+		t.Errorf("got details %v", details)
+	}
+	// a known reason gives the catalog error back
+	err = FromErrorInfo(CodeNotFound, "message 1 not found", ErrorInfo{Reason: "TEST_MESSAGE_NOT_FOUND", Domain: "test", Metadata: map[string]string{"id": "1"}})
+	if got := Trusted(Localize(err, "mg")).(*Error).Message(); got != "tsy hita ny hafatra 1" {
+		t.Errorf("got %q", got)
+	}
+
+	for name, messages := range map[string]map[string]string{
+		"no default message": {"fr": "x"},
+		"already registered": {"en": "x"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			testCatalog.Register("TEST_MESSAGE_NOT_FOUND", CodeNotFound, messages)
+		}()
+	}
+}
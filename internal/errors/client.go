@@ -0,0 +1,163 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// maxBodySize limits how much of an error response body is read.
+const maxBodySize = 1 << 20
+
+// FromGRPCStatus rebuilds the Error sent by another service, with its code, public message and details,
+// or the Batch if the status has the ErrorInfo details of its items (see Batch.GRPCStatus).
+// Returns nil if s is nil or OK.
+func FromGRPCStatus(s *status.Status) error {
+	if s == nil || s.Err() == nil {
+		return nil
+	}
+	ierr := &Error{code: FromGrpcCode(s.Code()), err: errors.New(s.Message()), msg: s.Message()}
+	var items []Item
+	for _, d := range s.Details() {
+		if d, ok := d.(*errdetails.ErrorInfo); ok {
+			if item, ok := batchItem(d); ok {
+				items = append(items, item)
+				continue
+			}
+		}
+		switch d := d.(type) {
+		case *errdetails.BadRequest:
+			br := BadRequest{Violations: make([]FieldViolation, 0, len(d.GetFieldViolations()))}
+			for _, v := range d.GetFieldViolations() {
+				br.Violations = append(br.Violations, FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+			}
+			ierr.details = append(ierr.details, br)
+		case *errdetails.RetryInfo:
+			ierr.details = append(ierr.details, RetryInfo{Delay: d.GetRetryDelay().AsDuration()})
+		case *errdetails.ErrorInfo:
+			ierr.details = append(ierr.details, ErrorInfo{Reason: d.GetReason(), Domain: d.GetDomain(), Metadata: d.GetMetadata()})
+		case *errdetails.LocalizedMessage:
+			ierr.details = append(ierr.details, LocalizedMessage{Locale: d.GetLocale(), Message: d.GetMessage()})
+		}
+	}
+	if len(items) > 0 {
+		return &Batch{items: items, code: ierr.code}
+	}
+	return ierr
+}
+
+// batchItem rebuilds an item of a Batch from its ErrorInfo, false if d is not one.
+func batchItem(d *errdetails.ErrorInfo) (Item, bool) {
+	md := d.GetMetadata()
+	key, ok := md["key"]
+	if !ok {
+		return Item{}, false
+	}
+	code, ok := ParseCode(md["code"])
+	if !ok {
+		return Item{}, false
+	}
+	msg := md["message"]
+	ierr := &Error{code: code, err: errors.New(msg), msg: msg}
+	if d.GetReason() != batchItemReason {
+		ierr.details = []Detail{ErrorInfo{Reason: d.GetReason(), Domain: d.GetDomain()}}
+	}
+	return Item{Key: key, Err: ierr}, true
+}
+
+// httpBody is the union of the problem+json body and of the legacy {"error": ...} / {"errors": [...]} bodies.
+type httpBody struct {
+	Code       string            `json:"code"`
+	Detail     string            `json:"detail"`
+	Reason     string            `json:"reason"`
+	Domain     string            `json:"domain"`
+	Metadata   map[string]string `json:"metadata"`
+	RetryAfter int               `json:"retry_after"`
+	Violations []struct {
+		Field  string `json:"field"`
+		Detail string `json:"detail"`
+	} `json:"violations"`
+	Items []struct {
+		Key    string `json:"key"`
+		Code   string `json:"code"`
+		Status int    `json:"status"`
+		Detail string `json:"detail"`
+		Reason string `json:"reason"`
+	} `json:"items"`
+
+	Err  string   `json:"error"`
+	Errs []string `json:"errors"`
+}
+
+// FromHTTPResponse rebuilds the Error sent by another service from an error response (status >= 400),
+// with its code, public message and details, or the Batch if the problem has items. The code is the "code" member of the problem, or is derived
+// from the status if there is none (several codes share a status). The body is read, closing it is still
+// up to the caller. Returns nil if resp is not an error response.
+func FromHTTPResponse(resp *http.Response) error {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	var body httpBody
+	// an undecodable body still gives an Error with the code of the status
+	_ = json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&body)
+	code, ok := ParseCode(body.Code)
+	if !ok {
+		code = FromHttpStatus(resp.StatusCode)
+	}
+
+	msg := body.Detail
+	switch {
+	case msg != "":
+	case body.Err != "":
+		msg = body.Err
+	case len(body.Errs) > 0:
+		msg = strings.Join(body.Errs, ", ")
+	default:
+		msg = code.Message()
+	}
+	ierr := &Error{code: code, err: errors.New(msg), msg: msg}
+	if len(body.Items) > 0 {
+		b := &Batch{items: make([]Item, 0, len(body.Items)), code: code}
+		for _, i := range body.Items {
+			icode, ok := ParseCode(i.Code)
+			if !ok {
+				icode = FromHttpStatus(i.Status)
+			}
+			item := &Error{code: icode, err: errors.New(i.Detail), msg: i.Detail}
+			if i.Reason != "" {
+				item.details = []Detail{ErrorInfo{Reason: i.Reason}}
+			}
+			b.items = append(b.items, Item{Key: i.Key, Err: item})
+		}
+		return b
+	}
+
+	if len(body.Violations) > 0 {
+		br := BadRequest{Violations: make([]FieldViolation, 0, len(body.Violations))}
+		for _, v := range body.Violations {
+			br.Violations = append(br.Violations, FieldViolation{Field: v.Field, Description: v.Detail})
+		}
+		ierr.details = append(ierr.details, br)
+	}
+	if body.Reason != "" {
+		ierr.details = append(ierr.details, ErrorInfo{Reason: body.Reason, Domain: body.Domain, Metadata: body.Metadata})
+	}
+	retryAfter := body.RetryAfter
+	if retryAfter == 0 {
+		retryAfter, _ = strconv.Atoi(resp.Header.Get("Retry-After"))
+	}
+	if retryAfter > 0 {
+		ierr.details = append(ierr.details, RetryInfo{Delay: time.Duration(retryAfter) * time.Second})
+	}
+	if lang := resp.Header.Get("Content-Language"); lang != "" && body.Detail != "" {
+		ierr.details = append(ierr.details, LocalizedMessage{Locale: lang, Message: body.Detail})
+	}
+	return ierr
+}
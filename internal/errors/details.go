@@ -0,0 +1,119 @@
+package errors
+
+import (
+	"errors"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Detail is a machine-readable detail attached to an Error,
+// sent as google.rpc status details over gRPC and as problem extension members over HTTP.
+// One of BadRequest, RetryInfo, ErrorInfo or LocalizedMessage.
+type Detail interface {
+	proto() protoiface.MessageV1
+}
+
+// BadRequest describes violations in a client request.
+type BadRequest struct {
+	Violations []FieldViolation
+}
+
+// FieldViolation describes a single bad request field.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// RetryInfo describes when the client can retry a failed request.
+type RetryInfo struct {
+	Delay time.Duration
+}
+
+// ErrorInfo describes the cause of the error with a stable reason, e.g. "MESSAGE_NOT_FOUND".
+type ErrorInfo struct {
+	Reason   string
+	Domain   string
+	Metadata map[string]string
+}
+
+// LocalizedMessage is an error message safe to return to the user, in the given locale (e.g. "fr").
+type LocalizedMessage struct {
+	Locale  string
+	Message string
+}
+
+func (d BadRequest) proto() protoiface.MessageV1 {
+	br := &errdetails.BadRequest{FieldViolations: make([]*errdetails.BadRequest_FieldViolation, 0, len(d.Violations))}
+	for _, v := range d.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: v.Field, Description: v.Description})
+	}
+	return br
+}
+
+func (d RetryInfo) proto() protoiface.MessageV1 {
+	return &errdetails.RetryInfo{RetryDelay: durationpb.New(d.Delay)}
+}
+
+func (d ErrorInfo) proto() protoiface.MessageV1 {
+	return &errdetails.ErrorInfo{Reason: d.Reason, Domain: d.Domain, Metadata: d.Metadata}
+}
+
+func (d LocalizedMessage) proto() protoiface.MessageV1 {
+	return &errdetails.LocalizedMessage{Locale: d.Locale, Message: d.Message}
+}
+
+// WithDetails wraps err in an Error carrying details, the code is the one of err (see GetCode).
+// err is not modified, so sentinel errors (ErrNotFound...) can be used safely.
+func WithDetails(err error, details ...Detail) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{code: GetCode(err), err: err, details: details}
+}
+
+// Details returns the details of e and of the error it wraps (see GetDetails), outermost first.
+func (e *Error) Details() []Detail {
+	return append(append([]Detail(nil), e.details...), GetDetails(e.err)...)
+}
+
+// GetDetails returns the details of the outermost trusted error of the chain of err (see Trusted),
+// or of the first Error of the chain or of a gRPC status error if there is none.
+// A Batch has no details, the ones of its items are theirs (see Batch.Items).
+func GetDetails(err error) []Detail {
+	switch terr := Trusted(err).(type) {
+	case *Error:
+		return terr.Details()
+	case *Batch:
+		return nil
+	}
+	var ierr *Error
+	if errors.As(err, &ierr) {
+		return ierr.Details()
+	}
+	if s, ok := status.FromError(err); ok && s.Err() != nil {
+		if ierr, ok := FromGRPCStatus(s).(*Error); ok {
+			return ierr.Details()
+		}
+	}
+	return nil
+}
+
+// withProtoDetails adds the details to s, they are dropped if they cannot be marshalled.
+func withProtoDetails(s *status.Status, details []Detail) *status.Status {
+	if len(details) == 0 {
+		return s
+	}
+	msgs := make([]protoiface.MessageV1, 0, len(details))
+	for _, d := range details {
+		msgs = append(msgs, d.proto())
+	}
+	ds, err := s.WithDetails(msgs...)
+	if err != nil {
+		return s
+	}
+	return ds
+}
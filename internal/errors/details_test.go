@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetailsGRPCRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail Detail
+	}{
+		{"bad request", BadRequest{Violations: []FieldViolation{{Field: "id", Description: "required"}, {Field: "msg", Description: "too long"}}}},
+		{"retry info", RetryInfo{Delay: 1500 * time.Millisecond}},
+		{"error info", ErrorInfo{Reason: "QUOTA", Domain: "hello", Metadata: map[string]string{"limit": "10"}}},
+		{"localized message", LocalizedMessage{Locale: "fr", Message: "quota dépassé"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WithDetails(WrapMsg(CodeResourceExhausted, errors.New("redis: 10 calls"), "quota exceeded"), tt.detail)
+
+			s := err.(*Error).GRPCStatus()
+			if s.Message() != "quota exceeded" {
+				t.Errorf("got status message %q, want the public message", s.Message())
+			}
+			rerr, ok := FromGRPCStatus(s).(*Error)
+			if !ok {
+				t.Fatalf("got %T, want *Error", FromGRPCStatus(s))
+			}
+			if rerr.Code() != CodeResourceExhausted || rerr.Message() != "quota exceeded" {
+				t.Errorf("got %v %q", rerr.Code(), rerr.Message())
+			}
+			if got := rerr.Details(); !reflect.DeepEqual(got, []Detail{tt.detail}) {
+				t.Errorf("got details %#v, want %#v", got, tt.detail)
+			}
+			if got := GetDetails(s.Err()); !reflect.DeepEqual(got, []Detail{tt.detail}) {
+				t.Errorf("got status error details %#v, want %#v", got, tt.detail)
+			}
+		})
+	}
+
+	t.Run("all", func(t *testing.T) {
+		details := make([]Detail, 0, len(tests))
+		for _, tt := range tests {
+			details = append(details, tt.detail)
+		}
+		err := WithDetails(New(CodeInvalidArgument, "bad"), details...)
+		if got := GetDetails(FromGRPCStatus(err.(*Error).GRPCStatus())); !reflect.DeepEqual(got, details) {
+			t.Errorf("got details %#v, want %#v", got, details)
+		}
+	})
+}
+
+func TestDetailsOrder(t *testing.T) {
+	inner := WithDetails(New(CodeNotFound, "no row"), ErrorInfo{Reason: "INNER"})
+	err := WithDetails(inner, ErrorInfo{Reason: "OUTER"}, RetryInfo{Delay: time.Second})
+	want := []Detail{ErrorInfo{Reason: "OUTER"}, RetryInfo{Delay: time.Second}, ErrorInfo{Reason: "INNER"}}
+	if got := GetDetails(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v: outermost first", got, want)
+	}
+	if GetCode(err) != CodeNotFound {
+		t.Errorf("got code %v, want the code of the wrapped error", GetCode(err))
+	}
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -37,20 +38,121 @@ const (
 	// http.StatusInternalServerError
 	// codes.Unknown
 	CodeUnknown
+	// 499 Client Closed Request
+	// codes.Canceled
+	CodeCanceled
+	// http.StatusGatewayTimeout
+	// codes.DeadlineExceeded
+	CodeDeadlineExceeded
+	// http.StatusTooManyRequests
+	// codes.ResourceExhausted
+	CodeResourceExhausted
+	// http.StatusBadRequest
+	// codes.FailedPrecondition
+	CodeFailedPrecondition
+	// http.StatusConflict
+	// codes.Aborted
+	CodeAborted
+	// http.StatusBadRequest
+	// codes.OutOfRange
+	CodeOutOfRange
+	// http.StatusServiceUnavailable
+	// codes.Unavailable
+	CodeUnavailable
+	// http.StatusInternalServerError
+	// codes.DataLoss
+	CodeDataLoss
 )
 
+// StatusClientClosedRequest is the non standard status (nginx) used when the client canceled the request.
+const StatusClientClosedRequest = 499
+
+var codeNames = map[Code]string{
+	CodeInvalidArgument:    "InvalidArgument",
+	CodeNotFound:           "NotFound",
+	CodeUnauthenticated:    "Unauthenticated",
+	CodePermissionDenied:   "PermissionDenied",
+	CodeInternal:           "Internal",
+	CodeAlreadyExists:      "AlreadyExists",
+	CodeUnimplemented:      "Unimplemented",
+	CodeUnknown:            "Unknown",
+	CodeCanceled:           "Canceled",
+	CodeDeadlineExceeded:   "DeadlineExceeded",
+	CodeResourceExhausted:  "ResourceExhausted",
+	CodeFailedPrecondition: "FailedPrecondition",
+	CodeAborted:            "Aborted",
+	CodeOutOfRange:         "OutOfRange",
+	CodeUnavailable:        "Unavailable",
+	CodeDataLoss:           "DataLoss",
+}
+
+// codeMessages are the public messages used when an Error has none.
+var codeMessages = map[Code]string{
+	CodeInvalidArgument:    "invalid argument",
+	CodeNotFound:           "not found",
+	CodeUnauthenticated:    "unauthenticated",
+	CodePermissionDenied:   "permission denied",
+	CodeInternal:           "internal error",
+	CodeAlreadyExists:      "already exists",
+	CodeUnimplemented:      "unimplemented",
+	CodeUnknown:            "unknown error",
+	CodeCanceled:           "canceled",
+	CodeDeadlineExceeded:   "deadline exceeded",
+	CodeResourceExhausted:  "resource exhausted",
+	CodeFailedPrecondition: "failed precondition",
+	CodeAborted:            "aborted",
+	CodeOutOfRange:         "out of range",
+	CodeUnavailable:        "unavailable",
+	CodeDataLoss:           "data loss",
+}
+
+// Message returns the default public message of the code.
+func (c Code) Message() string {
+	if msg, ok := codeMessages[c]; ok {
+		return msg
+	}
+	return codeMessages[CodeUnknown]
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// ParseCode returns the code named name (see String), false if there is none.
+func ParseCode(name string) (Code, bool) {
+	for c, n := range codeNames {
+		if n == name {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
 var (
 	ErrInternal error = &Error{code: CodeInternal}
 	ErrNotFound error = &Error{code: CodeNotFound}
 )
 
-// Trusted error
+// Trusted error, only its public message (Message) is sent to the clients,
+// its internal cause (Error) is for the logs.
 type Error struct {
-	err  error
-	code Code
-	// Time string `json:"timestamp"` // can be extended for richer error
+	err     error
+	code    Code
+	msg     string
+	details []Detail
+	stack   []uintptr // see SetCapture
+
+	retryable *bool // see WithRetryable
+
+	// see Catalog
+	entry *Entry
+	args  map[string]string
 }
 
+// Error returns the internal cause chain.
 func (e *Error) Error() string {
 	if e.err == nil {
 		return ""
@@ -58,23 +160,48 @@ func (e *Error) Error() string {
 	return e.err.Error()
 }
 
+// Message returns the public message, safe to send to the clients.
+// Falls back to the message of a wrapped Error, then to the default message of the code.
+func (e *Error) Message() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	if inner, ok := Trusted(e.err).(trustedError); ok {
+		return inner.Message()
+	}
+	return e.code.Message()
+}
+
 func (e *Error) Unwrap() error {
 	return e.err
 }
 
+// New creates an Error, txt is sent to the clients.
 func New(code Code, txt string) error {
-	return &Error{code: code, err: errors.New(txt)}
+	return &Error{code: code, err: errors.New(txt), msg: txt, stack: callers()}
 }
 
+// Newf creates an Error, the formatted message is sent to the clients.
 func Newf(code Code, format string, a ...any) error {
-	return &Error{err: errors.New(fmt.Sprintf(format, a...)), code: code}
+	msg := fmt.Sprintf(format, a...)
+	return &Error{err: errors.New(msg), code: code, msg: msg, stack: callers()}
 }
 
+// Wrap creates an Error caused by err, err is only logged and the default message of the code
+// (or the one of a wrapped Error) is sent to the clients.
 func Wrap(code Code, err error) error {
 	if err == nil {
 		err = errors.New("")
 	}
-	return &Error{code: code, err: err}
+	return &Error{code: code, err: err, stack: callers()}
+}
+
+// WrapMsg creates an Error caused by err, msg is sent to the clients and err is only logged.
+func WrapMsg(code Code, err error, msg string) error {
+	if err == nil {
+		err = errors.New(msg)
+	}
+	return &Error{code: code, err: err, msg: msg, stack: callers()}
 }
 
 func (e *Error) Code() Code {
@@ -83,12 +210,13 @@ func (e *Error) Code() Code {
 
 // to satisfy grpc's {GRPCStatus() *Status}
 func (e *Error) GRPCStatus() *status.Status {
-	return status.New(GrpcCode(e.code), e.Error())
+	return withProtoDetails(status.New(GrpcCode(e.code), e.Message()), e.Details())
 }
 
-// Trusted errors
+// Trusted errors, see Error.
 type Errors struct {
 	errs []error
+	msgs []string // public messages, one per err
 	code Code
 }
 
@@ -108,12 +236,26 @@ func (e *Errors) Errors() []string {
 	return s
 }
 
+// Messages returns the public messages, safe to send to the clients.
+func (e *Errors) Messages() []string {
+	return e.msgs
+}
+
+// Message returns the public messages joined, or the default message of the code if there is none.
+func (e *Errors) Message() string {
+	if len(e.msgs) == 0 {
+		return e.code.Message()
+	}
+	return strings.Join(e.msgs, ", ")
+}
+
+// NewS creates Errors, s are sent to the clients.
 func NewS(code Code, s ...string) error {
 	errs := make([]error, 0, len(s))
 	for _, v := range s {
 		errs = append(errs, errors.New(v))
 	}
-	return &Errors{code: code, errs: errs}
+	return &Errors{code: code, errs: errs, msgs: append([]string(nil), s...)}
 }
 
 func WrapS(code Code, errs ...error) error {
@@ -129,34 +271,97 @@ func WrapS(code Code, errs ...error) error {
 	e := &Errors{
 		code: code,
 		errs: make([]error, 0, n),
+		msgs: make([]string, 0, n),
 	}
 	for _, err := range errs {
 		if err != nil {
 			e.errs = append(e.errs, err)
+			e.msgs = append(e.msgs, publicMessage(code, err))
 		}
 	}
 	return e
 }
 
+// publicMessage returns the message of a trusted error or of a validation error (built from field names),
+// and the default message of code for anything else.
+func publicMessage(code Code, err error) string {
+	if terr, ok := Trusted(err).(trustedError); ok {
+		return terr.Message()
+	}
+	var ferr interface{ Field() string }
+	if errors.As(err, &ferr) {
+		return err.Error()
+	}
+	return code.Message()
+}
+
 func (e *Errors) Code() Code {
 	return e.code
 }
 
+// GRPCStatus sends the errors having a field (validation errors) as a BadRequest detail.
 func (e *Errors) GRPCStatus() *status.Status {
-	return status.New(GrpcCode(e.code), e.Error())
+	var br BadRequest
+	for i, err := range e.errs {
+		var ferr interface{ Field() string }
+		if errors.As(err, &ferr) {
+			br.Violations = append(br.Violations, FieldViolation{Field: ferr.Field(), Description: e.msgs[i]})
+		}
+	}
+	s := status.New(GrpcCode(e.code), e.Message())
+	if len(br.Violations) == 0 {
+		return s
+	}
+	return withProtoDetails(s, []Detail{br})
+}
+
+// trustedError is implemented by Error, Errors and Batch.
+type trustedError interface {
+	error
+	Code() Code
+	Message() string
+}
+
+// Trusted returns the outermost trusted error (*Error, *Errors or *Batch) of the chain of err, or nil.
+// Unlike errors.As, the children of an Errors/Batch are not preferred over the Errors/Batch itself.
+func Trusted(err error) error {
+	for err != nil {
+		switch terr := err.(type) {
+		case *Error, *Errors, *Batch:
+			return terr
+		case interface{ Unwrap() error }:
+			err = terr.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range terr.Unwrap() {
+				if t := Trusted(err); t != nil {
+					return t
+				}
+			}
+			return nil
+		default:
+			return nil
+		}
+	}
+	return nil
 }
 
 // helper function for getting the code out of an error,
-// returns CodeUnknown if the err is not an Error/Errors
+// context.Canceled and context.DeadlineExceeded are classified as CodeCanceled and CodeDeadlineExceeded,
+// gRPC status errors by their code, returns CodeUnknown if the err is not an Error/Errors/Batch
 func GetCode(err error) Code {
-	var ierr *Error
-	if errors.As(err, &ierr) {
-		return ierr.code
+	if terr, ok := Trusted(err).(trustedError); ok {
+		return terr.Code()
 	}
 
-	var ierrs *Errors
-	if errors.As(err, &ierrs) {
-		return ierrs.code
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return FromGrpcCode(s.Code())
 	}
 	return CodeUnknown
 }
@@ -177,11 +382,65 @@ func GrpcCode(code Code) codes.Code {
 		return codes.AlreadyExists
 	case CodeUnimplemented:
 		return codes.Unimplemented
+	case CodeCanceled:
+		return codes.Canceled
+	case CodeDeadlineExceeded:
+		return codes.DeadlineExceeded
+	case CodeResourceExhausted:
+		return codes.ResourceExhausted
+	case CodeFailedPrecondition:
+		return codes.FailedPrecondition
+	case CodeAborted:
+		return codes.Aborted
+	case CodeOutOfRange:
+		return codes.OutOfRange
+	case CodeUnavailable:
+		return codes.Unavailable
+	case CodeDataLoss:
+		return codes.DataLoss
 	default:
 		return codes.Unknown
 	}
 }
 
+// FromGrpcCode is the reverse of GrpcCode, codes.OK has no Code and gives CodeUnknown.
+func FromGrpcCode(code codes.Code) Code {
+	switch code {
+	case codes.InvalidArgument:
+		return CodeInvalidArgument
+	case codes.NotFound:
+		return CodeNotFound
+	case codes.Unauthenticated:
+		return CodeUnauthenticated
+	case codes.PermissionDenied:
+		return CodePermissionDenied
+	case codes.Internal:
+		return CodeInternal
+	case codes.AlreadyExists:
+		return CodeAlreadyExists
+	case codes.Unimplemented:
+		return CodeUnimplemented
+	case codes.Canceled:
+		return CodeCanceled
+	case codes.DeadlineExceeded:
+		return CodeDeadlineExceeded
+	case codes.ResourceExhausted:
+		return CodeResourceExhausted
+	case codes.FailedPrecondition:
+		return CodeFailedPrecondition
+	case codes.Aborted:
+		return CodeAborted
+	case codes.OutOfRange:
+		return CodeOutOfRange
+	case codes.Unavailable:
+		return CodeUnavailable
+	case codes.DataLoss:
+		return CodeDataLoss
+	default:
+		return CodeUnknown
+	}
+}
+
 func HttpStatus(code Code) int {
 	switch code {
 	case CodeInvalidArgument:
@@ -200,7 +459,58 @@ func HttpStatus(code Code) int {
 		return http.StatusNotImplemented
 	case CodeUnknown:
 		return http.StatusInternalServerError
+	case CodeCanceled:
+		return StatusClientClosedRequest
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case CodeAborted:
+		return http.StatusConflict
+	case CodeOutOfRange:
+		return http.StatusBadRequest
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeDataLoss:
+		return http.StatusInternalServerError
 	default:
 		return http.StatusInternalServerError
 	}
 }
+
+// FromHttpStatus is the reverse of HttpStatus, a status shared by several codes gives the most generic one
+// (e.g. http.StatusBadRequest gives CodeInvalidArgument).
+func FromHttpStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeAlreadyExists
+	case http.StatusPreconditionFailed:
+		return CodeFailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return CodeOutOfRange
+	case http.StatusTooManyRequests:
+		return CodeResourceExhausted
+	case StatusClientClosedRequest:
+		return CodeCanceled
+	case http.StatusInternalServerError:
+		return CodeInternal
+	case http.StatusNotImplemented:
+		return CodeUnimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeDeadlineExceeded
+	default:
+		return CodeUnknown
+	}
+}
@@ -0,0 +1,105 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodeMappings(t *testing.T) {
+	tests := []struct {
+		code     Code
+		grpc     codes.Code
+		status   int
+		fromHTTP Code // several codes share a status
+	}{
+		{CodeInvalidArgument, codes.InvalidArgument, http.StatusBadRequest, CodeInvalidArgument},
+		{CodeNotFound, codes.NotFound, http.StatusNotFound, CodeNotFound},
+		{CodeUnauthenticated, codes.Unauthenticated, http.StatusUnauthorized, CodeUnauthenticated},
+		{CodePermissionDenied, codes.PermissionDenied, http.StatusForbidden, CodePermissionDenied},
+		{CodeInternal, codes.Internal, http.StatusInternalServerError, CodeInternal},
+		{CodeAlreadyExists, codes.AlreadyExists, http.StatusConflict, CodeAlreadyExists},
+		{CodeUnimplemented, codes.Unimplemented, http.StatusNotImplemented, CodeUnimplemented},
+		{CodeUnknown, codes.Unknown, http.StatusInternalServerError, CodeInternal},
+		{CodeCanceled, codes.Canceled, StatusClientClosedRequest, CodeCanceled},
+		{CodeDeadlineExceeded, codes.DeadlineExceeded, http.StatusGatewayTimeout, CodeDeadlineExceeded},
+		{CodeResourceExhausted, codes.ResourceExhausted, http.StatusTooManyRequests, CodeResourceExhausted},
+		{CodeFailedPrecondition, codes.FailedPrecondition, http.StatusBadRequest, CodeInvalidArgument},
+		{CodeAborted, codes.Aborted, http.StatusConflict, CodeAlreadyExists},
+		{CodeOutOfRange, codes.OutOfRange, http.StatusBadRequest, CodeInvalidArgument},
+		{CodeUnavailable, codes.Unavailable, http.StatusServiceUnavailable, CodeUnavailable},
+		{CodeDataLoss, codes.DataLoss, http.StatusInternalServerError, CodeInternal},
+	}
+	if len(tests) != len(codeNames) {
+		t.Fatalf("%d codes tested, want %d", len(tests), len(codeNames))
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := GrpcCode(tt.code); got != tt.grpc {
+				t.Errorf("GrpcCode: got %v, want %v", got, tt.grpc)
+			}
+			if got := FromGrpcCode(tt.grpc); got != tt.code {
+				t.Errorf("FromGrpcCode: got %v, want %v", got, tt.code)
+			}
+			if got := HttpStatus(tt.code); got != tt.status {
+				t.Errorf("HttpStatus: got %d, want %d", got, tt.status)
+			}
+			if got := FromHttpStatus(tt.status); got != tt.fromHTTP {
+				t.Errorf("FromHttpStatus: got %v, want %v", got, tt.fromHTTP)
+			}
+			if got, ok := ParseCode(tt.code.String()); !ok || got != tt.code {
+				t.Errorf("ParseCode: got %v, %v", got, ok)
+			}
+		})
+	}
+
+	if got := FromGrpcCode(codes.OK); got != CodeUnknown {
+		t.Errorf("FromGrpcCode(OK): got %v, want %v", got, CodeUnknown)
+	}
+	// the statuses of no code
+	for s, want := range map[int]Code{
+		http.StatusBadGateway:                   CodeUnavailable,
+		http.StatusPreconditionFailed:           CodeFailedPrecondition,
+		http.StatusRequestedRangeNotSatisfiable: CodeOutOfRange,
+		http.StatusTeapot:                       CodeUnknown,
+	} {
+		if got := FromHttpStatus(s); got != want {
+			t.Errorf("FromHttpStatus(%d): got %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestGetCode(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"canceled", context.Canceled, CodeCanceled},
+		{"canceled context", fmt.Errorf("query: %w", canceled.Err()), CodeCanceled},
+		{"deadline exceeded", context.DeadlineExceeded, CodeDeadlineExceeded},
+		{"expired context", fmt.Errorf("query: %w", expired.Err()), CodeDeadlineExceeded},
+		{"trusted over context", Wrap(CodeUnavailable, context.DeadlineExceeded), CodeUnavailable},
+		{"error", fmt.Errorf("get: %w", New(CodeNotFound, "no row")), CodeNotFound},
+		{"errors", WrapS(CodeInvalidArgument, fmt.Errorf("bad")), CodeInvalidArgument},
+		{"batch", NewBatch(Explicit(CodeAborted), Index(0, New(CodeNotFound, "no row"))), CodeAborted},
+		{"grpc status", fmt.Errorf("call: %w", status.Error(codes.ResourceExhausted, "slow down")), CodeResourceExhausted},
+		{"untrusted", fmt.Errorf("boom"), CodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetCode(tt.err); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
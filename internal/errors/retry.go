@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// WithRetryable wraps err in an Error overriding whether it is retryable, the code is the one of err (see GetCode).
+func WithRetryable(err error, retryable bool) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{code: GetCode(err), err: err, retryable: &retryable}
+}
+
+// IsTemporary reports whether err is a transient failure that may resolve on its own:
+// CodeUnavailable, CodeDeadlineExceeded, CodeResourceExhausted (gRPC status errors included),
+// net.Error timeouts and errors with a Temporary() method returning true.
+func IsTemporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch GetCode(err) {
+	case CodeUnavailable, CodeDeadlineExceeded, CodeResourceExhausted:
+		return true
+	}
+
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	var terr interface{ Temporary() bool }
+	return errors.As(err, &terr) && terr.Temporary()
+}
+
+// IsRetryable reports whether the call that failed with err can be retried: the override set by WithRetryable if any,
+// otherwise a temporary error (see IsTemporary), CodeAborted or an error with a RetryInfo detail.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	for cur := err; cur != nil; {
+		ierr, ok := Trusted(cur).(*Error)
+		if !ok {
+			break
+		}
+		if ierr.retryable != nil {
+			return *ierr.retryable
+		}
+		cur = ierr.err
+	}
+
+	if IsTemporary(err) || GetCode(err) == CodeAborted {
+		return true
+	}
+	_, ok := RetryDelay(err)
+	return ok
+}
+
+// RetryDelay returns the delay of the first RetryInfo detail of err.
+func RetryDelay(err error) (time.Duration, bool) {
+	for _, d := range GetDetails(err) {
+		if ri, ok := d.(RetryInfo); ok {
+			return ri.Delay, true
+		}
+	}
+	return 0, false
+}
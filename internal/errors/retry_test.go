@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// netTimeout is a net.Error timing out.
+type netTimeout struct{}
+
+func (netTimeout) Error() string   { return "i/o timeout" }
+func (netTimeout) Timeout() bool   { return true }
+func (netTimeout) Temporary() bool { return false }
+
+// temporary is an error with a Temporary method.
+type temporary bool
+
+func (temporary) Error() string     { return "temporary" }
+func (t temporary) Temporary() bool { return bool(t) }
+
+func TestIsRetryable(t *testing.T) {
+	retryInfo := func(code codes.Code, delay time.Duration) error {
+		s, err := status.New(code, "boom").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Err()
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		temporary bool
+		retryable bool
+		delay     time.Duration // none if 0
+	}{
+		{"nil", nil, false, false, 0},
+		{"untrusted", fmt.Errorf("boom"), false, false, 0},
+		{"net timeout", fmt.Errorf("dial: %w", netTimeout{}), true, true, 0},
+		{"temporary", fmt.Errorf("read: %w", temporary(true)), true, true, 0},
+		{"not temporary", temporary(false), false, false, 0},
+		{"context deadline", context.DeadlineExceeded, true, true, 0},
+		{"context canceled", context.Canceled, false, false, 0},
+		{"retry info", WithDetails(New(CodeInvalidArgument, "bad"), RetryInfo{Delay: time.Second}), false, true, time.Second},
+		{"override", WithRetryable(New(CodeUnavailable, "down"), false), true, false, 0},
+		{"override beats retry info", WithRetryable(WithDetails(New(CodeInvalidArgument, "bad"), RetryInfo{Delay: time.Second}), false), false, false, time.Second},
+		{"override retryable", WithRetryable(New(CodeInvalidArgument, "bad"), true), false, true, 0},
+		{"outermost override", fmt.Errorf("call: %w", WithRetryable(WithRetryable(New(CodeAborted, "conflict"), true), false)), false, false, 0},
+		{"grpc status", status.Error(codes.Unavailable, "down"), true, true, 0},
+		{"grpc retry info", retryInfo(codes.FailedPrecondition, 3*time.Second), false, true, 3 * time.Second},
+		{"wrapped grpc retry info", Wrap(CodeInternal, retryInfo(codes.FailedPrecondition, 3*time.Second)), false, true, 3 * time.Second},
+	}
+	for code := range codeNames {
+		temp := code == CodeUnavailable || code == CodeDeadlineExceeded || code == CodeResourceExhausted
+		tests = append(tests, struct {
+			name      string
+			err       error
+			temporary bool
+			retryable bool
+			delay     time.Duration
+		}{code.String(), New(code, "boom"), temp, temp || code == CodeAborted, 0})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemporary(tt.err); got != tt.temporary {
+				t.Errorf("temporary: got %v, want %v", got, tt.temporary)
+			}
+			if got := IsRetryable(tt.err); got != tt.retryable {
+				t.Errorf("retryable: got %v, want %v", got, tt.retryable)
+			}
+			delay, ok := RetryDelay(tt.err)
+			if delay != tt.delay || ok != (tt.delay > 0) {
+				t.Errorf("delay: got %s, %v, want %s", delay, ok, tt.delay)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// Capture defines what is captured when an Error is created by New, Newf, Wrap or WrapMsg.
+type Capture int32
+
+const (
+	// nothing, the default
+	CaptureNone Capture = iota
+	// the function, file and line calling New, Newf, Wrap or WrapMsg
+	CaptureCaller
+	// the full stack
+	CaptureStack
+)
+
+const maxStackDepth = 32
+
+var capture atomic.Int32
+
+// SetCapture enables the caller/stack capture for the Errors created from now on.
+func SetCapture(c Capture) {
+	capture.Store(int32(c))
+}
+
+// callers returns the program counters of the caller of the Error constructor, depending on SetCapture.
+func callers() []uintptr {
+	var pcs []uintptr
+	switch Capture(capture.Load()) {
+	case CaptureCaller:
+		pcs = make([]uintptr, 1)
+	case CaptureStack:
+		pcs = make([]uintptr, maxStackDepth)
+	default:
+		return nil
+	}
+	// skip runtime.Callers, callers and the constructor
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// Frames returns the captured frames as "function file:line", outermost call last.
+func (e *Error) Frames() []string {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(e.stack)
+	s := make([]string, 0, len(e.stack))
+	for {
+		f, more := frames.Next()
+		s = append(s, f.Function+" "+f.File+":"+strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+	return s
+}
+
+// Format implements fmt.Formatter, %+v prints the code, the public message and the frames of every Error of the chain.
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%s\ncode: %s\nmessage: %s", e.Error(), e.code, e.Message())
+			for _, f := range e.Frames() {
+				fmt.Fprintf(s, "\n\t%s", f)
+			}
+			if inner, ok := Trusted(e.err).(*Error); ok {
+				fmt.Fprintf(s, "\ncaused by: %+v", inner)
+			}
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%"+string(verb), e.Error())
+	}
+}
+
+// LogValue implements slog.LogValuer, see LogValue.
+func (e *Error) LogValue() slog.Value {
+	return LogValue(e)
+}
+
+// LogValue returns err as a group with its message, cause chain, and the code, public message and frames
+// of its first Error, or as a string if there is no Error in the chain.
+func LogValue(err error) slog.Value {
+	ierr, ok := Trusted(err).(*Error)
+	if !ok {
+		return slog.StringValue(err.Error())
+	}
+
+	attrs := []slog.Attr{
+		slog.String("msg", err.Error()),
+		slog.String("code", ierr.code.String()),
+		slog.String("public", ierr.Message()),
+	}
+	// Error adds no text to its cause, so the same message is not repeated
+	var chain []string
+	for cerr := errors.Unwrap(err); cerr != nil; cerr = errors.Unwrap(cerr) {
+		if msg := cerr.Error(); len(chain) == 0 || chain[len(chain)-1] != msg {
+			chain = append(chain, msg)
+		}
+	}
+	if len(chain) > 0 {
+		attrs = append(attrs, slog.Any("chain", chain))
+	}
+	if frames := ierr.Frames(); len(frames) > 0 {
+		attrs = append(attrs, slog.Any("stack", frames))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,119 @@
+package errors
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newNotFound creates the Error of the tests, its caller frame is the one of newNotFound.
+func newNotFound() *Error {
+	return New(CodeNotFound, "no row").(*Error)
+}
+
+func setCapture(t *testing.T, c Capture) {
+	SetCapture(c)
+	t.Cleanup(func() { SetCapture(CaptureNone) })
+}
+
+func TestCapture(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture Capture
+		min     int
+		max     int
+	}{
+		{"none", CaptureNone, 0, 0},
+		{"caller", CaptureCaller, 1, 1},
+		{"stack", CaptureStack, 2, maxStackDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCapture(t, tt.capture)
+			frames := newNotFound().Frames()
+			if len(frames) < tt.min || len(frames) > tt.max {
+				t.Fatalf("got %d frames, want %d to %d", len(frames), tt.min, tt.max)
+			}
+			if len(frames) > 0 && !strings.HasPrefix(frames[0], "go-misc/internal/errors.newNotFound ") {
+				t.Errorf("got the frame %q, want the caller of New", frames[0])
+			}
+			if len(frames) > 0 && !strings.Contains(frames[0], "stack_test.go:") {
+				t.Errorf("got the frame %q, want its file and line", frames[0])
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	setCapture(t, CaptureCaller)
+	inner := newNotFound()
+	err := WrapMsg(CodeInternal, fmt.Errorf("load: %w", inner), "load failed").(*Error)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%v", "load: no row"},
+		{"%s", "load: no row"},
+		{"%q", `"load: no row"`},
+		{"%x", fmt.Sprintf("%x", "load: no row")},
+		{"%d", "%!d(string=load: no row)"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, err); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	got := fmt.Sprintf("%+v", err)
+	outer, cause, ok := strings.Cut(got, "\ncaused by: ")
+	if !ok {
+		t.Fatalf("no cause in %q", got)
+	}
+	for _, want := range []string{"load: no row\n", "\ncode: Internal\n", "\nmessage: load failed\n", "\n\tgo-misc/internal/errors.TestFormat "} {
+		if !strings.Contains(outer, want) {
+			t.Errorf("%q not in %q", want, outer)
+		}
+	}
+	for _, want := range []string{"no row\n", "\ncode: NotFound\n", "\nmessage: no row\n", "\n\tgo-misc/internal/errors.newNotFound "} {
+		if !strings.Contains(cause, want) {
+			t.Errorf("%q not in the cause %q", want, cause)
+		}
+	}
+}
+
+func TestLogValue(t *testing.T) {
+	setCapture(t, CaptureCaller)
+	err := fmt.Errorf("get: %w", WrapMsg(CodeInternal, fmt.Errorf("load: %w", newNotFound()), "load failed"))
+
+	v := LogValue(err)
+	if v.Kind() != slog.KindGroup {
+		t.Fatalf("got a %s, want a group", v.Kind())
+	}
+	attrs := map[string]string{}
+	for _, a := range v.Group() {
+		attrs[a.Key] = fmt.Sprint(a.Value.Any())
+	}
+	want := map[string]string{
+		"msg":    "get: load: no row",
+		"code":   "Internal",
+		"public": "load failed",
+		"chain":  "[load: no row no row]",
+	}
+	for k, w := range want {
+		if attrs[k] != w {
+			t.Errorf("%s: got %q, want %q", k, attrs[k], w)
+		}
+	}
+	if !strings.HasPrefix(attrs["stack"], "[go-misc/internal/errors.TestLogValue ") {
+		t.Errorf("got the stack %q", attrs["stack"])
+	}
+
+	if v := LogValue(fmt.Errorf("plain")); v.Kind() != slog.KindString || v.String() != "plain" {
+		t.Errorf("got %v, want the message of an untrusted error", v)
+	}
+	if v := newNotFound().LogValue(); v.Kind() != slog.KindGroup {
+		t.Errorf("got a %s, want a group", v.Kind())
+	}
+}
@@ -4,28 +4,101 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 
 	e "go-misc/internal/errors"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// ErrorUnaryServerInterceptor returns a new unary server interceptor for catching errors and only sending trusted errors.
+// MetadataKeyLocale is the metadata key holding the caller's locales, in the Accept-Language format.
+const MetadataKeyLocale = "accept-language"
+
+// ErrorUnaryServerInterceptor returns a new unary server interceptor for catching errors and only sending trusted errors,
+// catalog errors are rendered in the caller's locale.
 func ErrorUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	resp, err := handler(ctx, req)
+	return resp, trusted(localize(ctx, err))
+}
+
+// ErrorStreamServerInterceptor returns a new stream server interceptor for catching errors and only sending trusted errors.
+func ErrorStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return trusted(localize(ss.Context(), handler(srv, ss)))
+}
+
+// localize renders the catalog errors in the locales of the incoming metadata.
+func localize(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if al := md.Get(MetadataKeyLocale); len(al) > 0 {
+		return e.Localize(err, e.ParseAcceptLanguage(strings.Join(al, ","))...)
+	}
+	return err
+}
+
+// trusted unwraps err down to the first trusted error (Error/Errors) so its code and public message are sent.
+// Status errors (e.g. from a downstream call) are returned as is, a wrapped one is rebuilt with its own code
+// and message since status.FromError would send the message of the wrapping errors. Any other error is wrapped
+// so that its message is not sent.
+func trusted(err error) error {
 	if err == nil || err == io.EOF {
-		return resp, nil
+		return nil
 	}
 
-	var ierr *e.Error
-	if errors.As(err, &ierr) {
-		return resp, ierr
+	if terr := e.Trusted(err); terr != nil {
+		return terr
 	}
 
-	var ierrs *e.Errors
-	if errors.As(err, &ierrs) {
-		return resp, ierrs
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+	var gs interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &gs) {
+		s := gs.GRPCStatus()
+		return status.New(s.Code(), s.Message()).Err()
 	}
+	return e.Wrap(e.GetCode(err), err)
+}
+
+// ErrorUnaryClientInterceptor returns a new unary client interceptor rebuilding the trusted errors sent by the server
+// (see errors.FromGRPCStatus), so errors.GetCode works across services.
+func ErrorUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return fromStatus(invoker(ctx, method, req, reply, cc, opts...))
+}
 
-	return resp, err
+// ErrorStreamClientInterceptor returns a new stream client interceptor rebuilding the trusted errors sent by the server.
+func ErrorStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return &errClientStream{cs}, nil
+}
+
+type errClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errClientStream) SendMsg(m any) error {
+	return fromStatus(s.ClientStream.SendMsg(m))
+}
+
+func (s *errClientStream) RecvMsg(m any) error {
+	return fromStatus(s.ClientStream.RecvMsg(m))
+}
+
+// fromStatus rebuilds the trusted error of a status error, io.EOF and other errors are returned as is.
+func fromStatus(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return e.FromGRPCStatus(s)
 }
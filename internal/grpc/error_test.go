@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	e "go-misc/internal/errors"
+	"go-misc/internal/grpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"public message", e.New(e.CodeInvalidArgument, "invalid id"), codes.InvalidArgument, "invalid id"},
+		{"internal cause", e.Wrap(e.CodeInternal, errors.New("connect 10.0.0.3 as admin")), codes.Internal, "internal error"},
+		{"wrapped error", fmt.Errorf("query 10.0.0.3: %w", e.New(e.CodeNotFound, "no message")), codes.NotFound, "no message"},
+		{"untrusted error", errors.New("connect 10.0.0.3 as admin"), codes.Unknown, "unknown error"},
+		{"context error", fmt.Errorf("query 10.0.0.3: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "deadline exceeded"},
+		{"status", status.Error(codes.Unavailable, "db down"), codes.Unavailable, "db down"},
+		{"wrapped status", fmt.Errorf("query 10.0.0.3 as admin: %w", status.Error(codes.Unavailable, "db down")), codes.Unavailable, "db down"},
+	}
+	errs := make(map[string]error, len(tests))
+	for _, tt := range tests {
+		errs[tt.name] = tt.err
+	}
+	// fails with the error of the test named by the id of the request
+	failing := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, _ grpc.UnaryHandler) (any, error) {
+		return nil, errs[req.(*pb.SayRequest).GetId()]
+	}
+	cc := newTestConn(t, []grpc.ServerOption{grpc.ChainUnaryInterceptor(ErrorUnaryServerInterceptor, failing)})
+	client := pb.NewHelloServiceClient(cc)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Say(context.Background(), &pb.SayRequest{Id: tt.name})
+			s := status.Convert(err)
+			if s.Code() != tt.code || s.Message() != tt.message {
+				t.Errorf("got %s %q, want %s %q", s.Code(), s.Message(), tt.code, tt.message)
+			}
+		})
+	}
+}
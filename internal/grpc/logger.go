@@ -8,6 +8,9 @@ import (
 	"strings"
 	"time"
 
+	e "go-misc/internal/errors"
+
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -40,7 +43,7 @@ func evaluateLoggerOptions(opts []LoggerOption) *loggerOptions {
 func LoggerUnaryServerInterceptor(opts ...LoggerOption) grpc.UnaryServerInterceptor {
 	o := evaluateLoggerOptions(opts)
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
-		le := &logEntry{o.l, o.concise, o.sensitive, o.leak}
+		le := &logEntry{o.l, o.concise, o.sensitive, o.leak, false}
 		ctx = context.WithValue(ctx, ContextKeyLogEntry, le)
 
 		t := time.Now()
@@ -53,6 +56,64 @@ func LoggerUnaryServerInterceptor(opts ...LoggerOption) grpc.UnaryServerIntercep
 	}
 }
 
+// LoggerStreamServerInterceptor returns a new stream server interceptor logging one line per stream.
+func LoggerStreamServerInterceptor(opts ...LoggerOption) grpc.StreamServerInterceptor {
+	o := evaluateLoggerOptions(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		le := &logEntry{o.l, o.concise, o.sensitive, o.leak, false}
+		ws := wrapStream(ss)
+		ws.ctx = context.WithValue(ws.ctx, ContextKeyLogEntry, le)
+
+		t := time.Now()
+		defer func() {
+			le.error(err)
+			le.stream(ws)
+			le.log(ws.ctx, info.FullMethod, time.Since(t), err)
+		}()
+
+		return handler(srv, ws)
+	}
+}
+
+// LoggerUnaryClientInterceptor returns a new unary client interceptor logging outbound calls.
+func LoggerUnaryClientInterceptor(opts ...LoggerOption) grpc.UnaryClientInterceptor {
+	o := evaluateLoggerOptions(opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) (err error) {
+		le := &logEntry{o.l, o.concise, o.sensitive, o.leak, true}
+
+		t := time.Now()
+		defer func() {
+			le.error(err)
+			le.log(ctx, method, time.Since(t), err)
+		}()
+
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
+
+// LoggerStreamClientInterceptor returns a new stream client interceptor logging one line per outbound stream,
+// once the stream is done (RecvMsg returned an error or io.EOF, the response of a client-streaming RPC
+// was received, or ctx is done).
+func LoggerStreamClientInterceptor(opts ...LoggerOption) grpc.StreamClientInterceptor {
+	o := evaluateLoggerOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		le := &logEntry{o.l, o.concise, o.sensitive, o.leak, true}
+
+		t := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			le.error(err)
+			le.log(ctx, method, time.Since(t), err)
+			return nil, err
+		}
+
+		return wrapClientStream(ctx, cs, desc, func(err error) {
+			le.error(err)
+			le.log(ctx, method, time.Since(t), err)
+		}), nil
+	}
+}
+
 // LogEntryAttr helper func for setting slog.Attr to the logEntry.
 func LogEntryAttr(ctx context.Context, a ...any /* slog.Attr */) {
 	if entry, ok := ctx.Value(ContextKeyLogEntry).(*logEntry); ok {
@@ -73,19 +134,33 @@ type logEntry struct {
 	concise   bool
 	sensitive map[string]struct{}
 	leak      bool
+	client    bool // outbound call, the incoming metadata belongs to the parent call
 }
 
 func (le *logEntry) error(err error) {
 	if err != nil && err != io.EOF {
-		le.l = le.l.With(slog.String("error", err.Error()))
+		le.l = le.l.With(slog.Any("error", e.LogValue(err)))
 	}
 }
 
+func (le *logEntry) stream(ws *wStream) {
+	le.l = le.l.With(slog.Group("stream",
+		slog.Int64("sent", ws.sent.Load()),
+		slog.Int64("received", ws.recv.Load()),
+	))
+}
+
 func (le *logEntry) log(ctx context.Context, method string, d time.Duration, err error) {
 	reqID, ok := ctx.Value(ContextKeyRequestID).(string)
 	if ok {
 		le.l = le.l.With(slog.String("id", reqID))
 	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		le.l = le.l.With(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
 	code := status.Code(err)
 	le.l = le.l.With(
 		slog.String("method", method),
@@ -95,7 +170,7 @@ func (le *logEntry) log(ctx context.Context, method string, d time.Duration, err
 		),
 	)
 
-	if in, ok := metadata.FromIncomingContext(ctx); ok && !le.concise {
+	if in, ok := metadata.FromIncomingContext(ctx); ok && !le.concise && !le.client {
 		incomingAttr := grpcMetadataAttrs(in, le.leak, le.sensitive)
 		le.l = le.l.With(slog.Group("incoming", incomingAttr...))
 	}
@@ -171,7 +246,7 @@ func WithSensitive(s map[string]struct{}) LoggerOption {
 				o.sensitive[k] = struct{}{}
 			}
 		}
-		s["authorization"] = struct{}{}
+		o.sensitive["authorization"] = struct{}{}
 	}
 }
 
@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// wait waits for the single log line of a call, the stream client interceptor may log asynchronously.
+func (b *logBuffer) wait(t *testing.T) map[string]any {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if lines := b.lines(t); len(lines) > 0 {
+			time.Sleep(20 * time.Millisecond) // no extra line
+			if lines = b.lines(t); len(lines) != 1 {
+				t.Fatalf("%d log lines, want 1: %v", len(lines), lines)
+			}
+			return lines[0]
+		}
+	}
+	t.Fatal("no log line")
+	return nil
+}
+
+func newLoggedConn(t *testing.T) (*grpc.ClientConn, *logBuffer) {
+	buf := &logBuffer{}
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+	cc := newTestConn(t, nil, grpc.WithStreamInterceptor(LoggerStreamClientInterceptor(WithLogger(l), WithConcise(true))))
+	return cc, buf
+}
+
+func TestLoggerStreamClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(ctx context.Context, cc *grpc.ClientConn) error
+		cancel    bool
+		wantMsg   string
+		wantLevel string
+	}{
+		{
+			name: "server stream",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := serverStream(ctx, cc, "1")
+				return err
+			},
+			wantMsg:   "0 OK",
+			wantLevel: "INFO",
+		},
+		{
+			name: "server stream error",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := serverStream(ctx, cc, "missing")
+				if err == nil {
+					t.Error("no error")
+				}
+				return nil
+			},
+			wantMsg:   "5 NotFound",
+			wantLevel: "WARN",
+		},
+		{
+			name: "client stream",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := clientStream(ctx, cc, 2)
+				return err
+			},
+			wantMsg:   "0 OK",
+			wantLevel: "INFO",
+		},
+		{
+			name: "canceled",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := cc.NewStream(ctx, serverStreamDesc, "/test.Stream/Server")
+				return err
+			},
+			cancel:    true,
+			wantMsg:   "1 Canceled",
+			wantLevel: "WARN",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc, buf := newLoggedConn(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := tt.call(ctx, cc); err != nil {
+				t.Fatal(err)
+			}
+			if tt.cancel {
+				cancel()
+			}
+
+			line := buf.wait(t)
+			if line["msg"] != tt.wantMsg || line["level"] != tt.wantLevel {
+				t.Errorf("got %v %v, want %v %v", line["level"], line["msg"], tt.wantLevel, tt.wantMsg)
+			}
+		})
+	}
+}
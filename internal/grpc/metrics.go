@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+type metricsOptions struct {
+	reg       prometheus.Registerer
+	namespace string
+	buckets   []float64 // duration buckets, in seconds
+}
+
+type MetricsOption func(*metricsOptions)
+
+func evaluateMetricsOptions(opts []MetricsOption) *metricsOptions {
+	opt := &metricsOptions{
+		reg:       prometheus.DefaultRegisterer,
+		namespace: "",
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// Metrics records the RED metrics (rate, errors, duration) of the calls labelled by service, method and code.
+// The same collectors are shared by the unary and stream interceptors.
+type Metrics struct {
+	handled  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	sent     *prometheus.CounterVec
+	received *prometheus.CounterVec
+}
+
+// NewMetrics registers the collectors, it panics if they are already registered.
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	o := evaluateMetricsOptions(opts)
+	labels := []string{"service", "method", "code"}
+	m := &Metrics{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "grpc_server",
+			Name:      "handled_total",
+			Help:      "Total number of RPCs completed on the server.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: "grpc_server",
+			Name:      "handling_seconds",
+			Help:      "Duration of RPCs handled by the server.",
+			Buckets:   o.buckets,
+		}, labels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Subsystem: "grpc_server",
+			Name:      "in_flight",
+			Help:      "Number of RPCs being handled by the server.",
+		}, []string{"service", "method"}),
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "grpc_server",
+			Name:      "msg_sent_total",
+			Help:      "Total number of stream messages sent by the server.",
+		}, []string{"service", "method"}),
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "grpc_server",
+			Name:      "msg_received_total",
+			Help:      "Total number of stream messages received by the server.",
+		}, []string{"service", "method"}),
+	}
+	o.reg.MustRegister(m.handled, m.duration, m.inFlight, m.sent, m.received)
+	return m
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor recording metrics.
+func (m *Metrics) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+	service, method := splitMethod(info.FullMethod)
+	g := m.inFlight.WithLabelValues(service, method)
+	g.Inc()
+	defer g.Dec()
+
+	t := time.Now()
+	defer func() { m.observe(service, method, time.Since(t), err) }()
+
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor returns a new stream server interceptor recording metrics.
+func (m *Metrics) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	service, method := splitMethod(info.FullMethod)
+	g := m.inFlight.WithLabelValues(service, method)
+	g.Inc()
+	defer g.Dec()
+
+	ws := wrapStream(ss)
+	sent, recv := ws.sent.Load(), ws.recv.Load()
+	t := time.Now()
+	defer func() {
+		m.sent.WithLabelValues(service, method).Add(float64(ws.sent.Load() - sent))
+		m.received.WithLabelValues(service, method).Add(float64(ws.recv.Load() - recv))
+		m.observe(service, method, time.Since(t), err)
+	}()
+
+	return handler(srv, ws)
+}
+
+func (m *Metrics) observe(service, method string, d time.Duration, err error) {
+	code := status.Code(trusted(err)).String()
+	m.handled.WithLabelValues(service, method, code).Inc()
+	m.duration.WithLabelValues(service, method, code).Observe(d.Seconds())
+}
+
+// splitMethod splits "/package.service/method".
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
+
+// WithRegisterer is a functional option to register the collectors somewhere else than
+// prometheus.DefaultRegisterer, e.g. a private prometheus.NewRegistry() in tests.
+func WithRegisterer(reg prometheus.Registerer) MetricsOption {
+	return func(o *metricsOptions) {
+		o.reg = reg
+	}
+}
+
+func WithNamespace(namespace string) MetricsOption {
+	return func(o *metricsOptions) {
+		o.namespace = namespace
+	}
+}
+
+func WithBuckets(buckets []float64) MetricsOption {
+	return func(o *metricsOptions) {
+		o.buckets = buckets
+	}
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-misc/internal/grpc/pb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(WithRegisterer(reg), WithNamespace("ns"))
+	cc := newTestConn(t, []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(m.UnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(m.StreamServerInterceptor),
+	})
+	ctx := context.Background()
+
+	client := pb.NewHelloServiceClient(cc)
+	client.Say(ctx, &pb.SayRequest{Id: "1"})
+	client.Say(ctx, &pb.SayRequest{Id: "missing"})
+	if _, err := serverStream(ctx, cc, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP ns_grpc_server_handled_total Total number of RPCs completed on the server.
+# TYPE ns_grpc_server_handled_total counter
+ns_grpc_server_handled_total{code="NotFound",method="Say",service="pb.HelloService"} 1
+ns_grpc_server_handled_total{code="OK",method="Say",service="pb.HelloService"} 1
+ns_grpc_server_handled_total{code="OK",method="Server",service="test.Stream"} 1
+# HELP ns_grpc_server_in_flight Number of RPCs being handled by the server.
+# TYPE ns_grpc_server_in_flight gauge
+ns_grpc_server_in_flight{method="Say",service="pb.HelloService"} 0
+ns_grpc_server_in_flight{method="Server",service="test.Stream"} 0
+# HELP ns_grpc_server_msg_received_total Total number of stream messages received by the server.
+# TYPE ns_grpc_server_msg_received_total counter
+ns_grpc_server_msg_received_total{method="Server",service="test.Stream"} 1
+# HELP ns_grpc_server_msg_sent_total Total number of stream messages sent by the server.
+# TYPE ns_grpc_server_msg_sent_total counter
+ns_grpc_server_msg_sent_total{method="Server",service="test.Stream"} 3
+`
+	names := []string{"ns_grpc_server_handled_total", "ns_grpc_server_in_flight", "ns_grpc_server_msg_received_total", "ns_grpc_server_msg_sent_total"}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), names...); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(m.duration, "ns_grpc_server_handling_seconds"); n != 3 {
+		t.Errorf("got %d duration series, want 3", n)
+	}
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"runtime/debug"
 
@@ -19,25 +20,27 @@ func RecoverUnaryServerInterceptor(ctx context.Context, req any, info *grpc.Unar
 				ctx,
 				slog.String("stack", stack),
 			)
-			err = e.Newf(e.CodeInternal, "panic caught: %v", re)
+			// the panic is logged, not sent
+			err = e.Wrap(e.CodeInternal, fmt.Errorf("panic caught: %v", re))
 		}
 	}()
 
 	return handler(ctx, req)
 }
 
-// // StreamServerInterceptor returns a new streaming server interceptor for panic recovery.
-// func RecoverStreamServerInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
-// 	defer func() {
-// 		if re := recover(); re != nil {
-// 			stack := string(debug.Stack())
-// 			LogEntryAttr(
-// 				ctx,
-// 				slog.String("panic", fmt.Sprintf("panic caught: %v (internal)", re)),
-// 				slog.String("stack", stack),
-// 			)
-// 		}
-// 	}()
+// RecoverStreamServerInterceptor returns a new streaming server interceptor for panic recovery.
+func RecoverStreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if re := recover(); re != nil {
+			stack := string(debug.Stack())
+			LogEntryAttr(
+				ss.Context(),
+				slog.String("stack", stack),
+			)
+			// the panic is logged, not sent
+			err = e.Wrap(e.CodeInternal, fmt.Errorf("panic caught: %v", re))
+		}
+	}()
 
-// 	return handler(srv, stream)
-// }
+	return handler(srv, ss)
+}
@@ -2,9 +2,12 @@ package grpc
 
 import (
 	"context"
+	"strings"
+
+	"go-misc/internal/requestid"
 
-	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type contextKey int
@@ -17,9 +20,106 @@ const (
 	ContextKeyLogEntry
 )
 
-func RequestIDUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-	id := uuid.New()
-	ctx = context.WithValue(ctx, ContextKeyRequestID, id.String())
+// MetadataKeyRequestID is the metadata key used for forwarding the request ID to other services.
+const MetadataKeyRequestID = "x-request-id"
+
+type requestIDOptions struct {
+	key       string              // incoming metadata key holding the request ID, the ID is echoed back in it
+	generator requestid.Generator // used when the incoming request ID is missing or invalid
+	maxLength int                 // maximum length of an incoming request ID
+}
+
+type RequestIDOption func(*requestIDOptions)
+
+func evaluateRequestIDOptions(opts []RequestIDOption) *requestIDOptions {
+	opt := &requestIDOptions{
+		key:       MetadataKeyRequestID,
+		generator: requestid.UUIDv4,
+		maxLength: requestid.MaxLength,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// requestID returns the incoming request ID or a new one, and the metadata to echo it back.
+func (o *requestIDOptions) requestID(ctx context.Context) (string, metadata.MD) {
+	var v string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md.Get(o.key); len(vs) > 0 {
+			v = vs[0]
+		}
+	}
+	id, ok := requestid.FromIncoming(o.key, v, o.maxLength)
+	if !ok {
+		id = o.generator()
+	}
+	// a traceparent is not echoed back as is, only its trace-id
+	key := o.key
+	if strings.EqualFold(key, requestid.KeyTraceparent) {
+		key = MetadataKeyRequestID
+	}
+	return id, metadata.Pairs(key, id)
+}
+
+func RequestIDUnaryServerInterceptor(opts ...RequestIDOption) grpc.UnaryServerInterceptor {
+	o := evaluateRequestIDOptions(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id, md := o.requestID(ctx)
+		grpc.SetHeader(ctx, md)
+		ctx = context.WithValue(ctx, ContextKeyRequestID, id)
+
+		return handler(ctx, req)
+	}
+}
+
+func RequestIDStreamServerInterceptor(opts ...RequestIDOption) grpc.StreamServerInterceptor {
+	o := evaluateRequestIDOptions(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id, md := o.requestID(ss.Context())
+		ss.SetHeader(md)
+		ws := wrapStream(ss)
+		ws.ctx = context.WithValue(ws.ctx, ContextKeyRequestID, id)
+
+		return handler(srv, ws)
+	}
+}
+
+func RequestIDUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+func RequestIDStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+// outgoingRequestID forwards the request ID found in ctx (if any) as outgoing metadata.
+func outgoingRequestID(ctx context.Context) context.Context {
+	id, ok := ctx.Value(ContextKeyRequestID).(string)
+	if !ok || id == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataKeyRequestID, id)
+}
+
+// WithRequestIDMetadata is a functional option to read the request ID from another metadata key.
+// "traceparent" can be used to take the trace-id of a W3C Trace Context.
+func WithRequestIDMetadata(key string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.key = strings.ToLower(key)
+	}
+}
+
+// WithRequestIDGenerator is a functional option to use another generator (requestid.UUIDv4, requestid.UUIDv7, requestid.ULID...).
+func WithRequestIDGenerator(g requestid.Generator) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.generator = g
+	}
+}
 
-	return handler(ctx, req)
+func WithRequestIDMaxLength(n int) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.maxLength = n
+	}
 }
@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"go-misc/internal/grpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestID(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	generate := func() string { return "generated" }
+
+	tests := []struct {
+		name string
+		opts []RequestIDOption
+		md   metadata.MD
+		want string
+		echo string // metadata key echoing the ID
+	}{
+		{"incoming", nil, metadata.Pairs(MetadataKeyRequestID, "req-1"), "req-1", MetadataKeyRequestID},
+		{"missing", nil, nil, "generated", MetadataKeyRequestID},
+		{"invalid", nil, metadata.Pairs(MetadataKeyRequestID, "req 1"), "generated", MetadataKeyRequestID},
+		{"too long", []RequestIDOption{WithRequestIDMaxLength(4)}, metadata.Pairs(MetadataKeyRequestID, "req-1"), "generated", MetadataKeyRequestID},
+		{"other key", []RequestIDOption{WithRequestIDMetadata("X-Correlation-ID")}, metadata.Pairs("x-correlation-id", "req-1"), "req-1", "x-correlation-id"},
+		{"traceparent", []RequestIDOption{WithRequestIDMetadata("traceparent")}, metadata.Pairs("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"), traceID, MetadataKeyRequestID},
+		{"invalid traceparent", []RequestIDOption{WithRequestIDMetadata("traceparent")}, metadata.Pairs("traceparent", "ff-"+traceID+"-00f067aa0ba902b7-01"), "generated", MetadataKeyRequestID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]RequestIDOption{WithRequestIDGenerator(generate)}, tt.opts...)
+			var got, gotStream string
+			cc := newTestConn(t, []grpc.ServerOption{
+				grpc.ChainUnaryInterceptor(RequestIDUnaryServerInterceptor(opts...), func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+					got, _ = ctx.Value(ContextKeyRequestID).(string)
+					return handler(ctx, req)
+				}),
+				grpc.ChainStreamInterceptor(RequestIDStreamServerInterceptor(opts...), func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+					gotStream, _ = ss.Context().Value(ContextKeyRequestID).(string)
+					return handler(srv, ss)
+				}),
+			})
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+
+			var header metadata.MD
+			if _, err := pb.NewHelloServiceClient(cc).Say(ctx, &pb.SayRequest{Id: "1"}, grpc.Header(&header)); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got request ID %q, want %q", got, tt.want)
+			}
+			if echo := header.Get(tt.echo); len(echo) != 1 || echo[0] != tt.want {
+				t.Errorf("got %s %q, want %q", tt.echo, echo, tt.want)
+			}
+
+			cs, err := cc.NewStream(ctx, serverStreamDesc, "/test.Stream/Server")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cs.SendMsg(&pb.SayRequest{Id: "1"}); err != nil {
+				t.Fatal(err)
+			}
+			cs.CloseSend()
+			if header, err = cs.Header(); err != nil {
+				t.Fatal(err)
+			}
+			for cs.RecvMsg(&pb.SayResponse{}) == nil {
+			}
+			if gotStream != tt.want {
+				t.Errorf("stream: got request ID %q, want %q", gotStream, tt.want)
+			}
+			if echo := header.Get(tt.echo); len(echo) != 1 || echo[0] != tt.want {
+				t.Errorf("stream: got %s %q, want %q", tt.echo, echo, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// wStream wraps a grpc.ServerStream for overriding its context (request ID, log entry...)
+// and for counting the messages sent and received.
+type wStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent atomic.Int64
+	recv atomic.Int64
+}
+
+// wrapStream returns ss if it is already wrapped, so interceptors down the chain share the same counters.
+func wrapStream(ss grpc.ServerStream) *wStream {
+	if ws, ok := ss.(*wStream); ok {
+		return ws
+	}
+	return &wStream{ServerStream: ss, ctx: ss.Context()}
+}
+
+func (s *wStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *wStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	}
+	return err
+}
+
+func (s *wStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.recv.Add(1)
+	}
+	return err
+}
+
+// wClientStream wraps a grpc.ClientStream for calling done once the stream is finished:
+// when RecvMsg returns an error (io.EOF being a success), after the response of a client-streaming RPC,
+// or when the context of the stream is done.
+type wClientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	once sync.Once
+	stop func() bool // stops the call of done on the context
+	done func(err error)
+}
+
+func wrapClientStream(ctx context.Context, cs grpc.ClientStream, desc *grpc.StreamDesc, done func(err error)) *wClientStream {
+	s := &wClientStream{ClientStream: cs, desc: desc, done: done}
+	s.stop = context.AfterFunc(ctx, func() {
+		s.finish(status.FromContextError(ctx.Err()).Err())
+	})
+	return s
+}
+
+func (s *wClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		// the single response of a client-streaming (or unary) RPC
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *wClientStream) finish(err error) {
+	s.once.Do(func() {
+		s.stop()
+		s.done(err)
+	})
+}
@@ -0,0 +1,256 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-misc/internal/grpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testService implements the unary pb.HelloService and, with testStreamDesc, a server-streaming
+// and a client-streaming RPC. The id "missing" fails with NotFound.
+type testService struct {
+	pb.UnimplementedHelloServiceServer
+}
+
+func (testService) Say(_ context.Context, req *pb.SayRequest) (*pb.SayResponse, error) {
+	if req.GetId() == "missing" {
+		return nil, status.Error(codes.NotFound, "missing")
+	}
+	return &pb.SayResponse{Message: "hello " + req.GetId()}, nil
+}
+
+var (
+	serverStreamDesc = &grpc.StreamDesc{StreamName: "Server", ServerStreams: true}
+	clientStreamDesc = &grpc.StreamDesc{StreamName: "Client", ClientStreams: true}
+)
+
+var testStreamDesc = grpc.ServiceDesc{
+	ServiceName: "test.Stream",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    serverStreamDesc.StreamName,
+			ServerStreams: true,
+			// sends 3 responses, fails with NotFound for "missing" and panics for "panic"
+			Handler: func(_ any, ss grpc.ServerStream) error {
+				req := &pb.SayRequest{}
+				if err := ss.RecvMsg(req); err != nil {
+					return err
+				}
+				switch req.GetId() {
+				case "missing":
+					return status.Error(codes.NotFound, "missing")
+				case "panic":
+					panic("boom")
+				}
+				for i := 0; i < 3; i++ {
+					if err := ss.SendMsg(&pb.SayResponse{Message: strconv.Itoa(i)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			StreamName:    clientStreamDesc.StreamName,
+			ClientStreams: true,
+			// responds with the number of requests
+			Handler: func(_ any, ss grpc.ServerStream) error {
+				n := 0
+				for {
+					err := ss.RecvMsg(&pb.SayRequest{})
+					if err == io.EOF {
+						return ss.SendMsg(&pb.SayResponse{Message: strconv.Itoa(n)})
+					}
+					if err != nil {
+						return err
+					}
+					n++
+				}
+			},
+		},
+	},
+}
+
+// newTestConn serves testService in memory and returns a connection to it.
+func newTestConn(t *testing.T, srvOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(srvOpts...)
+	pb.RegisterHelloServiceServer(s, testService{})
+	s.RegisterService(&testStreamDesc, struct{}{})
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+	)
+	cc, err := grpc.Dial("bufnet", dialOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+// serverStream calls the server-streaming RPC with id and reads the responses until the end of the stream.
+func serverStream(ctx context.Context, cc *grpc.ClientConn, id string) (int, error) {
+	cs, err := cc.NewStream(ctx, serverStreamDesc, "/test.Stream/Server")
+	if err != nil {
+		return 0, err
+	}
+	if err := cs.SendMsg(&pb.SayRequest{Id: id}); err != nil {
+		return 0, err
+	}
+	if err := cs.CloseSend(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		err := cs.RecvMsg(&pb.SayResponse{})
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// clientStream calls the client-streaming RPC with n requests, like a generated CloseAndRecv.
+func clientStream(ctx context.Context, cc *grpc.ClientConn, n int) (string, error) {
+	cs, err := cc.NewStream(ctx, clientStreamDesc, "/test.Stream/Client")
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < n; i++ {
+		if err := cs.SendMsg(&pb.SayRequest{Id: strconv.Itoa(i)}); err != nil {
+			return "", err
+		}
+	}
+	if err := cs.CloseSend(); err != nil {
+		return "", err
+	}
+	resp := &pb.SayResponse{}
+	if err := cs.RecvMsg(resp); err != nil {
+		return "", err
+	}
+	return resp.GetMessage(), nil
+}
+
+// logBuffer collects the JSON log lines of a logger, it is safe for concurrent use.
+type logBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) lines(t *testing.T) []map[string]any {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	var lines []map[string]any
+	for _, l := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		if len(l) == 0 {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal(l, &m); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestStreamServerInterceptors(t *testing.T) {
+	buf := &logBuffer{}
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+	var ids []string
+	var mtx sync.Mutex
+	cc := newTestConn(t, []grpc.ServerOption{grpc.ChainStreamInterceptor(
+		RequestIDStreamServerInterceptor(),
+		LoggerStreamServerInterceptor(WithLogger(l), WithConcise(true)),
+		ErrorStreamServerInterceptor,
+		RecoverStreamServerInterceptor,
+		// the handlers see the context set by the interceptors
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			id, _ := ss.Context().Value(ContextKeyRequestID).(string)
+			mtx.Lock()
+			ids = append(ids, id)
+			mtx.Unlock()
+			if _, ok := ss.Context().Value(ContextKeyLogEntry).(*logEntry); !ok {
+				return status.Error(codes.Internal, "no log entry")
+			}
+			return handler(srv, ss)
+		},
+	)})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		call     func() error
+		code     codes.Code
+		level    string
+		sent     float64
+		received float64
+	}{
+		{"server stream", func() error { _, err := serverStream(ctx, cc, "1"); return err }, codes.OK, "INFO", 3, 1},
+		{"client stream", func() error { _, err := clientStream(ctx, cc, 2); return err }, codes.OK, "INFO", 1, 2},
+		{"error", func() error { _, err := serverStream(ctx, cc, "missing"); return err }, codes.NotFound, "WARN", 0, 1},
+		{"panic", func() error { _, err := serverStream(ctx, cc, "panic"); return err }, codes.Internal, "ERROR", 0, 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.code {
+				t.Fatalf("got %v, want code %v", err, tt.code)
+			}
+			lines := buf.lines(t)
+			if len(lines) != i+1 {
+				t.Fatalf("%d log lines, want %d", len(lines), i+1)
+			}
+			line := lines[i]
+			if line["level"] != tt.level || line["msg"] != fmt.Sprintf("%d %s", tt.code, tt.code) {
+				t.Errorf("got %v %v, want %s %d %s", line["level"], line["msg"], tt.level, tt.code, tt.code)
+			}
+			stream, _ := line["stream"].(map[string]any)
+			if stream["sent"] != tt.sent || stream["received"] != tt.received {
+				t.Errorf("got stream %v, want %v sent and %v received", stream, tt.sent, tt.received)
+			}
+			mtx.Lock()
+			id := ids[i]
+			mtx.Unlock()
+			if id == "" || line["id"] != id {
+				t.Errorf("got request ID %v, want %q", line["id"], id)
+			}
+			_, stack := line["stack"]
+			if tt.code == codes.Internal && (!stack || !strings.Contains(fmt.Sprint(line["error"]), "panic caught: boom")) {
+				t.Errorf("panic not logged: %v", line)
+			}
+		})
+	}
+}
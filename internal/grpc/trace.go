@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const tracerName = "go-misc/internal/grpc"
+
+type traceOptions struct {
+	tp         trace.TracerProvider
+	propagator propagation.TextMapPropagator // W3C traceparent and tracestate by default
+}
+
+type TraceOption func(*traceOptions)
+
+func evaluateTraceOptions(opts []TraceOption) *traceOptions {
+	opt := &traceOptions{
+		tp:         otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// TraceUnaryServerInterceptor returns a new unary server interceptor starting a span per call,
+// continuing the trace of the incoming traceparent/tracestate if any.
+// It must be placed before the logger so the trace_id and span_id are added to the log entry.
+func TraceUnaryServerInterceptor(opts ...TraceOption) grpc.UnaryServerInterceptor {
+	o := evaluateTraceOptions(opts)
+	tracer := o.tp.Tracer(tracerName)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+		ctx, span := o.start(ctx, tracer, info.FullMethod)
+		defer func() { endSpan(span, err) }()
+
+		return handler(ctx, req)
+	}
+}
+
+// TraceStreamServerInterceptor returns a new stream server interceptor starting a span per stream.
+func TraceStreamServerInterceptor(opts ...TraceOption) grpc.StreamServerInterceptor {
+	o := evaluateTraceOptions(opts)
+	tracer := o.tp.Tracer(tracerName)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ws := wrapStream(ss)
+		var span trace.Span
+		ws.ctx, span = o.start(ws.ctx, tracer, info.FullMethod)
+		defer func() { endSpan(span, err) }()
+
+		return handler(srv, ws)
+	}
+}
+
+// TraceUnaryClientInterceptor returns a new unary client interceptor starting a client span per call
+// and propagating it in the outgoing metadata.
+func TraceUnaryClientInterceptor(opts ...TraceOption) grpc.UnaryClientInterceptor {
+	o := evaluateTraceOptions(opts)
+	tracer := o.tp.Tracer(tracerName)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) (err error) {
+		ctx, span := o.startClient(ctx, tracer, method)
+		defer func() { endSpan(span, err) }()
+
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
+
+// TraceStreamClientInterceptor returns a new stream client interceptor starting a client span per stream,
+// the span ends once the stream is done (see LoggerStreamClientInterceptor).
+func TraceStreamClientInterceptor(opts ...TraceOption) grpc.StreamClientInterceptor {
+	o := evaluateTraceOptions(opts)
+	tracer := o.tp.Tracer(tracerName)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := o.startClient(ctx, tracer, method)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		return wrapClientStream(ctx, cs, desc, func(err error) { endSpan(span, err) }), nil
+	}
+}
+
+func (o *traceOptions) start(ctx context.Context, tracer trace.Tracer, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = o.propagator.Extract(ctx, metadataCarrier(md))
+	return tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(rpcAttrs(fullMethod)...),
+	)
+}
+
+func (o *traceOptions) startClient(ctx context.Context, tracer trace.Tracer, fullMethod string) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(rpcAttrs(fullMethod)...),
+	)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	o.propagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// endSpan sets the status code of the call and ends the span.
+func endSpan(span trace.Span, err error) {
+	code := status.Code(trusted(err))
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if code != codes.OK {
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+	span.End()
+}
+
+// rpcAttrs splits "/package.service/method".
+func rpcAttrs(fullMethod string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if ok {
+		attrs = append(attrs, semconv.RPCService(service), semconv.RPCMethod(method))
+	}
+	return attrs
+}
+
+// metadataCarrier adapts metadata.MD to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	vs := metadata.MD(c).Get(key)
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func WithTracerProvider(tp trace.TracerProvider) TraceOption {
+	return func(o *traceOptions) {
+		o.tp = tp
+	}
+}
+
+func WithPropagator(p propagation.TextMapPropagator) TraceOption {
+	return func(o *traceOptions) {
+		o.propagator = p
+	}
+}
@@ -0,0 +1,134 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-misc/internal/grpc/pb"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// newTracedConn traces both sides of the calls, with spans exported in memory.
+func newTracedConn(t *testing.T) (*grpc.ClientConn, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	cc := newTestConn(t,
+		[]grpc.ServerOption{
+			grpc.UnaryInterceptor(TraceUnaryServerInterceptor(WithTracerProvider(tp))),
+			grpc.StreamInterceptor(TraceStreamServerInterceptor(WithTracerProvider(tp))),
+		},
+		grpc.WithUnaryInterceptor(TraceUnaryClientInterceptor(WithTracerProvider(tp))),
+		grpc.WithStreamInterceptor(TraceStreamClientInterceptor(WithTracerProvider(tp))),
+	)
+	return cc, exp
+}
+
+// spans returns the client and server spans of a single call, checking that the server continued the trace
+// of the client (traceparent propagated in the metadata).
+func spans(t *testing.T, exp *tracetest.InMemoryExporter) (client, server tracetest.SpanStub) {
+	t.Helper()
+	// the server span may end after the client received the response
+	stubs := exp.GetSpans()
+	for deadline := time.Now().Add(time.Second); len(stubs) < 2 && time.Now().Before(deadline); stubs = exp.GetSpans() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(stubs) != 2 {
+		t.Fatalf("%d spans, want 2", len(stubs))
+	}
+	for _, s := range stubs {
+		switch s.SpanKind {
+		case trace.SpanKindClient:
+			client = s
+		case trace.SpanKindServer:
+			server = s
+		}
+	}
+	if server.Parent.SpanID() != client.SpanContext.SpanID() || server.SpanContext.TraceID() != client.SpanContext.TraceID() {
+		t.Errorf("server span %v is not a child of client span %v", server.Parent, client.SpanContext)
+	}
+	return client, server
+}
+
+func TestTraceInterceptors(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       func(ctx context.Context, cc *grpc.ClientConn) error
+		wantSpan   string
+		wantStatus otelcodes.Code
+	}{
+		{
+			name: "unary",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewHelloServiceClient(cc).Say(ctx, &pb.SayRequest{Id: "1"})
+				return err
+			},
+			wantSpan:   "pb.HelloService/Say",
+			wantStatus: otelcodes.Unset,
+		},
+		{
+			name: "unary error",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := pb.NewHelloServiceClient(cc).Say(ctx, &pb.SayRequest{Id: "missing"})
+				if err == nil {
+					t.Error("no error")
+				}
+				return nil
+			},
+			wantSpan:   "pb.HelloService/Say",
+			wantStatus: otelcodes.Error,
+		},
+		{
+			name: "server stream",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := serverStream(ctx, cc, "1")
+				return err
+			},
+			wantSpan:   "test.Stream/Server",
+			wantStatus: otelcodes.Unset,
+		},
+		{
+			name: "server stream error",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := serverStream(ctx, cc, "missing")
+				if err == nil {
+					t.Error("no error")
+				}
+				return nil
+			},
+			wantSpan:   "test.Stream/Server",
+			wantStatus: otelcodes.Error,
+		},
+		{
+			name: "client stream",
+			call: func(ctx context.Context, cc *grpc.ClientConn) error {
+				_, err := clientStream(ctx, cc, 2)
+				return err
+			},
+			wantSpan:   "test.Stream/Client",
+			wantStatus: otelcodes.Unset,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc, exp := newTracedConn(t)
+			if err := tt.call(context.Background(), cc); err != nil {
+				t.Fatal(err)
+			}
+
+			client, server := spans(t, exp)
+			for _, s := range []tracetest.SpanStub{client, server} {
+				if s.Name != tt.wantSpan || s.Status.Code != tt.wantStatus {
+					t.Errorf("%v span %q with status %v, want %q with %v", s.SpanKind, s.Name, s.Status.Code, tt.wantSpan, tt.wantStatus)
+				}
+			}
+		})
+	}
+}
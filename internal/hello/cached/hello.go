@@ -0,0 +1,49 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-misc/internal/cache"
+	"go-misc/internal/hello"
+)
+
+// HelloRepository fronts any hello.HelloRepository with a cache, NotFound results are cached as negative entries
+// and the cached message is invalidated on every write.
+type HelloRepository struct {
+	c    *cache.Cache[string, hello.Message]
+	r    hello.HelloRepository
+	opts []cache.LoadOption
+}
+
+// https://github.com/uber-go/guide/blob/master/style.md#verify-interface-compliance
+var _ hello.HelloRepository = (*HelloRepository)(nil)
+
+// NewRepository decorates r, opts override the defaults (NotFound cached for 10s).
+func NewRepository(c *cache.Cache[string, hello.Message], r hello.HelloRepository, opts ...cache.LoadOption) *HelloRepository {
+	return &HelloRepository{
+		c:    c,
+		r:    r,
+		opts: append([]cache.LoadOption{cache.WithNegativeTTL(10 * time.Second)}, opts...),
+	}
+}
+
+// Get returns the error of r as is, a cached NotFound keeps its code, message and reason (see cache.ErrNotFound).
+func (r *HelloRepository) Get(id string) (hello.Message, error) {
+	return r.c.GetOrLoad(context.Background(), id, func(context.Context) (hello.Message, error) {
+		return r.r.Get(id)
+	}, r.opts...)
+}
+
+// Save writes m then invalidates its cached message, a Get loading the previous message concurrently
+// does not cache it (see cache.Cache.Delete).
+func (r *HelloRepository) Save(m hello.Message) error {
+	if err := r.r.Save(m); err != nil {
+		return err
+	}
+	if err := r.c.Delete(m.Id); err != nil {
+		return fmt.Errorf("invalidate message %q: %w", m.Id, err)
+	}
+	return nil
+}
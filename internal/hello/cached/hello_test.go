@@ -0,0 +1,173 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go-misc/internal/cache"
+	e "go-misc/internal/errors"
+	"go-misc/internal/hello"
+	"go-misc/internal/hello/inmem"
+	"go-misc/internal/resp"
+)
+
+// countingRepository counts the calls of Get.
+type countingRepository struct {
+	hello.HelloRepository
+	gets atomic.Int32
+}
+
+func (r *countingRepository) Get(id string) (hello.Message, error) {
+	r.gets.Add(1)
+	return r.HelloRepository.Get(id)
+}
+
+// goneRepository fails with a NotFound error which is not from the hello catalog.
+type goneRepository struct {
+	hello.HelloRepository
+}
+
+func (goneRepository) Get(string) (hello.Message, error) {
+	return hello.Message{}, e.WithDetails(e.New(e.CodeNotFound, "gone for good"), e.ErrorInfo{Reason: "GONE", Domain: "other"})
+}
+
+func newRepository(t *testing.T, r hello.HelloRepository) (*HelloRepository, *countingRepository) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c, err := cache.NewCache[string, hello.Message](ctx, cache.WithBackend(cache.LRU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := &countingRepository{HelloRepository: r}
+	return NewRepository(c, cr), cr
+}
+
+func TestGetNegative(t *testing.T) {
+	t.Run("catalog error", func(t *testing.T) {
+		repo, cr := newRepository(t, inmem.NewRepository())
+		for i := 0; i < 2; i++ {
+			_, err := repo.Get("nope")
+			if !hello.ErrMessageNotFound.Is(err) || !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("call %d: got %v, want MESSAGE_NOT_FOUND", i, err)
+			}
+			lerr := e.Localize(err, "fr")
+			if got, want := e.Trusted(lerr).(*e.Error).Message(), "message nope introuvable"; got != want {
+				t.Errorf("call %d: got localized message %q, want %q", i, got, want)
+			}
+		}
+		if n := cr.gets.Load(); n != 1 {
+			t.Errorf("got %d loads, want 1", n)
+		}
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		repo, cr := newRepository(t, goneRepository{})
+		for i := 0; i < 2; i++ {
+			_, err := repo.Get("nope")
+			if e.GetCode(err) != e.CodeNotFound || !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("call %d: got %v, want NotFound", i, err)
+			}
+			if got := e.Trusted(err).(*e.Error).Message(); got != "gone for good" {
+				t.Errorf("call %d: got message %q", i, got)
+			}
+			reason := ""
+			for _, d := range e.GetDetails(err) {
+				if ei, ok := d.(e.ErrorInfo); ok {
+					reason = ei.Reason + "/" + ei.Domain
+				}
+			}
+			if reason != "GONE/other" {
+				t.Errorf("call %d: got reason %q, want GONE/other", i, reason)
+			}
+		}
+		if n := cr.gets.Load(); n != 1 {
+			t.Errorf("got %d loads, want 1", n)
+		}
+	})
+}
+
+func TestSaveInvalidates(t *testing.T) {
+	repo, cr := newRepository(t, inmem.NewRepository())
+	if _, err := repo.Get("new"); err == nil {
+		t.Fatal("no error")
+	}
+	if err := repo.Save(hello.Message{Id: "new", English: "New"}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := repo.Get("new")
+	if err != nil || m.English != "New" {
+		t.Fatalf("got %v, %v", m, err)
+	}
+	if err := repo.Save(hello.Message{Id: "new", English: "Newer"}); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := repo.Get("new"); m.English != "Newer" {
+		t.Errorf("got %q, want the saved message", m.English)
+	}
+	if n := cr.gets.Load(); n != 3 {
+		t.Errorf("got %d loads, want 3", n)
+	}
+}
+
+// slowRepository reads the message then waits for release, like a slow query.
+type slowRepository struct {
+	hello.HelloRepository
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (r *slowRepository) Get(id string) (hello.Message, error) {
+	m, err := r.HelloRepository.Get(id)
+	r.read <- struct{}{}
+	<-r.release
+	return m, err
+}
+
+func TestSaveDuringGet(t *testing.T) {
+	slow := &slowRepository{HelloRepository: inmem.NewRepository(), read: make(chan struct{}, 2), release: make(chan struct{})}
+	repo, _ := newRepository(t, slow)
+	if err := slow.HelloRepository.Save(hello.Message{Id: "new", English: "New"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan hello.Message)
+	go func() {
+		m, _ := repo.Get("new")
+		got <- m
+	}()
+	<-slow.read
+	if err := repo.Save(hello.Message{Id: "new", English: "Newer"}); err != nil {
+		t.Fatal(err)
+	}
+	close(slow.release)
+	if m := <-got; m.English != "New" {
+		t.Errorf("got %q from the Get started before Save", m.English)
+	}
+	if m, _ := repo.Get("new"); m.English != "Newer" {
+		t.Errorf("got %q, want the saved message", m.English)
+	}
+}
+
+func TestSaveFailedInvalidation(t *testing.T) {
+	srv, err := resp.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := resp.NewClient(srv.Addr())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c, err := cache.NewCache[string, hello.Message](ctx, cache.WithBackend(cache.TwoTier(cache.LRU, cache.Remote(client, "hello:"), 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(c, inmem.NewRepository())
+
+	// the other replicas would keep serving the cached message
+	srv.Close()
+	if err := repo.Save(hello.Message{Id: "new", English: "New"}); err == nil {
+		t.Error("no error when the shared cache cannot be invalidated")
+	}
+}
@@ -0,0 +1,13 @@
+package hello
+
+import (
+	e "go-misc/internal/errors"
+)
+
+var catalog = e.NewCatalog("hello")
+
+var ErrMessageNotFound = catalog.Register("MESSAGE_NOT_FOUND", e.CodeNotFound, map[string]string{
+	"en": "message {id} not found",
+	"fr": "message {id} introuvable",
+	"mg": "tsy hita ny hafatra {id}",
+})
@@ -2,7 +2,6 @@ package inmem
 
 import (
 	"fmt"
-	e "go-misc/internal/errors"
 	"go-misc/internal/hello"
 	"sync"
 )
@@ -33,8 +32,16 @@ func (r *HelloRepository) Get(id string) (hello.Message, error) {
 
 	m, ok := r.messages[id]
 	if !ok {
-		return hello.Message{}, fmt.Errorf("get message %q: %w", id, e.New(e.CodeNotFound, "message not found"))
+		return hello.Message{}, fmt.Errorf("get message %q: %w", id, hello.ErrMessageNotFound.New("id", id))
 	}
 
 	return *m, nil
 }
+
+func (r *HelloRepository) Save(m hello.Message) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.messages[m.Id] = &m
+	return nil
+}
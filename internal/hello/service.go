@@ -3,7 +3,6 @@ package hello
 import (
 	"context"
 	"fmt"
-	"go-misc/internal/cache"
 	e "go-misc/internal/errors"
 	"go-misc/internal/validator"
 
@@ -12,17 +11,18 @@ import (
 
 type HelloRepository interface {
 	Get(address string) (Message, error)
+	// Save inserts or replaces the message of m.Id.
+	Save(m Message) error
 }
 
 type service struct {
 	l *slog.Logger
 	v *validator.Validation
-	c *cache.Cache
 	r HelloRepository
 }
 
-func NewService(l *slog.Logger, v *validator.Validation, c *cache.Cache, r HelloRepository) *service {
-	return &service{l: l, v: v, c: c, r: r}
+func NewService(l *slog.Logger, v *validator.Validation, r HelloRepository) *service {
+	return &service{l: l, v: v, r: r}
 }
 
 func (s *service) Say(ctx context.Context, id string) (string, error) {
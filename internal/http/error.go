@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	e "go-misc/internal/errors"
+)
+
+// Do sends req with client (http.DefaultClient if nil) and rebuilds the trusted error sent by another service
+// for an error response (see errors.FromHTTPResponse), so errors.GetCode works across services.
+// The body of an error response is closed and no response is returned with the error.
+func Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.FromHTTPResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	e "go-misc/internal/errors"
+)
+
+func TestDo(t *testing.T) {
+	// codes sharing a status with another one
+	codes := []e.Code{e.CodeAborted, e.CodeAlreadyExists, e.CodeFailedPrecondition, e.CodeOutOfRange, e.CodeDataLoss, e.CodeNotFound}
+	for _, code := range codes {
+		t.Run(code.String(), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				EncodeError(r.Context(), w, e.New(code, "boom"))
+			}))
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			resp, err := Do(srv.Client(), req)
+			if resp != nil {
+				t.Errorf("response returned with the error")
+			}
+			if got := e.GetCode(err); got != code {
+				t.Errorf("got code %v, want %v", got, code)
+			}
+			if got := e.Trusted(err).(*e.Error).Message(); got != "boom" {
+				t.Errorf("got message %q, want %q", got, "boom")
+			}
+		})
+	}
+
+	t.Run("no code member", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"error":"gone"}`, http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		_, err := Do(nil, req)
+		if got := e.GetCode(err); got != e.CodeUnavailable {
+			t.Errorf("got code %v, want %v", got, e.CodeUnavailable)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := Do(nil, req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("got %v, %v", resp, err)
+		}
+		resp.Body.Close()
+	})
+}
@@ -0,0 +1,19 @@
+package http
+
+import (
+	"net/http"
+
+	e "go-misc/internal/errors"
+)
+
+// Locale sets the locales of the Accept-Language header in the request context,
+// EncodeError uses them for rendering the catalog errors.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if al := r.Header.Get("Accept-Language"); al != "" {
+			r = r.WithContext(e.ContextWithLocales(r.Context(), e.ParseAcceptLanguage(al)...))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
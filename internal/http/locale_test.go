@@ -0,0 +1,53 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-misc/internal/hello"
+)
+
+func TestLocale(t *testing.T) {
+	h := Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		EncodeError(r.Context(), w, hello.ErrMessageNotFound.New("id", "1"))
+	}))
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		detail         string
+		language       string
+	}{
+		{"none", "", "message 1 not found", ""},
+		{"en", "en-US,en;q=0.9", "message 1 not found", ""},
+		{"fr", "fr-FR,fr;q=0.9,en;q=0.8", "message 1 introuvable", "fr"},
+		{"mg", "mg", "tsy hita ny hafatra 1", "mg"},
+		{"unknown", "de", "message 1 not found", ""},
+		{"fallback", "de;q=0.9,mg;q=0.8,fr;q=0.7", "tsy hita ny hafatra 1", "mg"},
+		{"quality", "fr;q=0.5,mg", "tsy hita ny hafatra 1", "mg"},
+		{"invalid", "fr;q=x", "message 1 not found", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			var p Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Detail != tt.detail || p.Status != http.StatusNotFound || p.Reason != "MESSAGE_NOT_FOUND" {
+				t.Errorf("got %d %s %q, want 404 MESSAGE_NOT_FOUND %q", p.Status, p.Reason, p.Detail, tt.detail)
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.language {
+				t.Errorf("got Content-Language %q, want %q", got, tt.language)
+			}
+		})
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	e "go-misc/internal/errors"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type loggerOptions struct {
@@ -76,7 +80,7 @@ type logEntry struct {
 
 func (le *logEntry) error() {
 	if le.err != nil {
-		le.l = le.l.With(slog.String("error", le.err.Error()))
+		le.l = le.l.With(slog.Any("error", e.LogValue(le.err)))
 	}
 }
 
@@ -85,6 +89,12 @@ func (le *logEntry) req(r *http.Request) {
 	if ok {
 		le.l = le.l.With(slog.String("id", reqID))
 	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		le.l = le.l.With(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
 
 	requestAttr := make([]any, 0, 7) // slog.Attr
 	requestAttr = append(requestAttr,
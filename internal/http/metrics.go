@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metricsOptions struct {
+	reg       prometheus.Registerer
+	namespace string
+	buckets   []float64 // duration buckets, in seconds
+}
+
+type MetricsOption func(*metricsOptions)
+
+func evaluateMetricsOptions(opts []MetricsOption) *metricsOptions {
+	opt := &metricsOptions{
+		reg:       prometheus.DefaultRegisterer,
+		namespace: "",
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// Metrics records the RED metrics (rate, errors, duration) of the requests, labelled by mux route template
+// so that path parameters do not blow up the cardinality.
+// The collectors are registered once, it panics if they are already registered.
+func Metrics(opts ...MetricsOption) func(next http.Handler) http.Handler {
+	o := evaluateMetricsOptions(opts)
+	labels := []string{"method", "route", "code"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: o.namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests.",
+	}, labels)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: o.namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests.",
+		Buckets:   o.buckets,
+	}, labels)
+	size := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: o.namespace,
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "Size of HTTP responses.",
+		Buckets:   prometheus.ExponentialBuckets(100, 10, 6),
+	}, labels)
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: o.namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests being served.",
+	}, []string{"method", "route"})
+	o.reg.MustRegister(requests, duration, size, inFlight)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := "unknown"
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+			g := inFlight.WithLabelValues(r.Method, route)
+			g.Inc()
+			defer g.Dec()
+
+			ww := &wWriter{w, false, http.StatusOK, 0}
+			t := time.Now()
+			defer func() {
+				code := strconv.Itoa(ww.code)
+				requests.WithLabelValues(r.Method, route, code).Inc()
+				duration.WithLabelValues(r.Method, route, code).Observe(time.Since(t).Seconds())
+				size.WithLabelValues(r.Method, route, code).Observe(float64(ww.size))
+			}()
+
+			next.ServeHTTP(reimplement(ww), r)
+		})
+	}
+}
+
+// WithRegisterer is a functional option to register the collectors somewhere else than
+// prometheus.DefaultRegisterer, e.g. a private prometheus.NewRegistry() in tests.
+func WithRegisterer(reg prometheus.Registerer) MetricsOption {
+	return func(o *metricsOptions) {
+		o.reg = reg
+	}
+}
+
+func WithNamespace(namespace string) MetricsOption {
+	return func(o *metricsOptions) {
+		o.namespace = namespace
+	}
+}
+
+func WithBuckets(buckets []float64) MetricsOption {
+	return func(o *metricsOptions) {
+		o.buckets = buckets
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := mux.NewRouter()
+	r.Use(Metrics(WithRegisterer(reg), WithNamespace("ns")))
+	r.HandleFunc("/v1/say/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("hello"))
+	})
+
+	for _, id := range []string{"1", "2", "missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/say/"+id, nil))
+	}
+
+	want := `
+# HELP ns_http_requests_total Total number of HTTP requests.
+# TYPE ns_http_requests_total counter
+ns_http_requests_total{code="200",method="GET",route="/v1/say/{id}"} 2
+ns_http_requests_total{code="404",method="GET",route="/v1/say/{id}"} 1
+# HELP ns_http_requests_in_flight Number of HTTP requests being served.
+# TYPE ns_http_requests_in_flight gauge
+ns_http_requests_in_flight{method="GET",route="/v1/say/{id}"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "ns_http_requests_total", "ns_http_requests_in_flight"); err != nil {
+		t.Error(err)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			h := m.GetHistogram()
+			if h == nil {
+				continue
+			}
+			code := ""
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "code" {
+					code = l.GetValue()
+				}
+			}
+			count, sum := map[string]uint64{"200": 2, "404": 1}[code], 0.0
+			if mf.GetName() == "ns_http_response_size_bytes" {
+				sum = map[string]float64{"200": 10, "404": 0}[code]
+			}
+			if h.GetSampleCount() != count || (sum > 0 && h.GetSampleSum() != sum) {
+				t.Errorf("%s{code=%q}: got %d samples of sum %g, want %d", mf.GetName(), code, h.GetSampleCount(), h.GetSampleSum(), count)
+			}
+		}
+	}
+}
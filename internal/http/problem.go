@@ -0,0 +1,129 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+
+	e "go-misc/internal/errors"
+)
+
+// ContentTypeProblem is the media type of Problem.
+const ContentTypeProblem = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body.
+// https://datatracker.ietf.org/doc/html/rfc7807
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"` // request ID
+
+	// extension members
+	Code       string            `json:"code,omitempty"` // errors.Code, several codes share a status
+	Violations []Violation       `json:"violations,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Domain     string            `json:"domain,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	RetryAfter int               `json:"retry_after,omitempty"` // seconds, also sent as Retry-After header
+	Language   string            `json:"-"`                     // locale of Detail, sent as Content-Language header
+	Items      []Item            `json:"items,omitempty"`       // per-item results of a batch (errors.Batch)
+}
+
+// Violation describes why a field of the request is invalid.
+type Violation struct {
+	Field  string `json:"field,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// NewProblem creates a Problem from err, only the public messages of trusted errors (Error/Errors) are detailed,
+// any other error gives an opaque 500. Catalog errors are rendered in the locale set by the Locale middleware.
+func NewProblem(ctx context.Context, err error) *Problem {
+	err = e.Localize(err, e.LocalesFromContext(ctx)...)
+	p := &Problem{Type: "about:blank"}
+	if reqID, ok := ctx.Value(ContextKeyRequestID).(string); ok {
+		p.Instance = reqID
+	}
+
+	switch terr := e.Trusted(err).(type) {
+	case *e.Error:
+		p.Code = terr.Code().String()
+		p.Status = e.HttpStatus(terr.Code())
+		p.Detail = terr.Message()
+		p.details(terr.Details())
+	case *e.Errors:
+		p.Code = terr.Code().String()
+		p.Status = e.HttpStatus(terr.Code())
+		p.Detail = terr.Message()
+		msgs := terr.Messages()
+		for i, err := range terr.Unwrap() {
+			p.Violations = append(p.Violations, newViolation(err, msgs[i]))
+		}
+	case *e.Batch:
+		p.Code = terr.Code().String()
+		p.Status = e.HttpStatus(terr.Code())
+		p.Detail = terr.Message()
+		for _, i := range terr.Items() {
+			item := Item{Key: i.Key, Code: i.Code().String(), Status: e.HttpStatus(i.Code()), Detail: i.Message()}
+			for _, d := range e.GetDetails(i.Err) {
+				if ei, ok := d.(e.ErrorInfo); ok {
+					item.Reason = ei.Reason
+					break
+				}
+			}
+			p.Items = append(p.Items, item)
+		}
+	default:
+		p.Status = http.StatusInternalServerError
+	}
+	p.Title = http.StatusText(p.Status)
+	if p.Title == "" {
+		p.Title = e.FromHttpStatus(p.Status).String()
+	}
+
+	return p
+}
+
+// details sets the extension members from the error details, the first detail of a kind wins.
+func (p *Problem) details(details []e.Detail) {
+	for _, d := range details {
+		switch d := d.(type) {
+		case e.BadRequest:
+			for _, v := range d.Violations {
+				p.Violations = append(p.Violations, Violation{Field: v.Field, Detail: v.Description})
+			}
+		case e.ErrorInfo:
+			if p.Reason == "" {
+				p.Reason, p.Domain, p.Metadata = d.Reason, d.Domain, d.Metadata
+			}
+		case e.RetryInfo:
+			if p.RetryAfter == 0 {
+				p.RetryAfter = int(math.Ceil(d.Delay.Seconds()))
+			}
+		case e.LocalizedMessage:
+			if p.Language == "" {
+				p.Detail, p.Language = d.Message, d.Locale
+			}
+		}
+	}
+}
+
+// Item is the result of a failed item of a batch.
+type Item struct {
+	Key    string `json:"key"`
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// newViolation uses the field name of validation errors (validator.ValidationError).
+func newViolation(err error, msg string) Violation {
+	var ferr interface{ Field() string }
+	if errors.As(err, &ferr) {
+		return Violation{Field: ferr.Field(), Detail: msg}
+	}
+	return Violation{Detail: msg}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	e "go-misc/internal/errors"
+)
+
+func TestProblemDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		detail  e.Detail
+		problem Problem
+		header  http.Header
+	}{
+		{
+			name:    "bad request",
+			detail:  e.BadRequest{Violations: []e.FieldViolation{{Field: "id", Description: "required"}}},
+			problem: Problem{Violations: []Violation{{Field: "id", Detail: "required"}}},
+		},
+		{
+			name:    "retry info",
+			detail:  e.RetryInfo{Delay: 1500 * time.Millisecond},
+			problem: Problem{RetryAfter: 2},
+			header:  http.Header{"Retry-After": {"2"}},
+		},
+		{
+			name:    "error info",
+			detail:  e.ErrorInfo{Reason: "QUOTA", Domain: "hello", Metadata: map[string]string{"limit": "10"}},
+			problem: Problem{Reason: "QUOTA", Domain: "hello", Metadata: map[string]string{"limit": "10"}},
+		},
+		{
+			name:    "localized message",
+			detail:  e.LocalizedMessage{Locale: "fr", Message: "quota dépassé"},
+			problem: Problem{Detail: "quota dépassé", Language: "fr"},
+			header:  http.Header{"Content-Language": {"fr"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := e.WithDetails(e.WrapMsg(e.CodeResourceExhausted, context.DeadlineExceeded, "quota exceeded"), tt.detail)
+
+			want := tt.problem
+			want.Type, want.Title, want.Status, want.Code = "about:blank", "Too Many Requests", http.StatusTooManyRequests, "ResourceExhausted"
+			if want.Detail == "" {
+				want.Detail = "quota exceeded"
+			}
+			if p := NewProblem(context.Background(), err); !reflect.DeepEqual(*p, want) {
+				t.Errorf("got %+v, want %+v", *p, want)
+			}
+
+			w := httptest.NewRecorder()
+			EncodeError(context.Background(), w, err)
+			for k := range tt.header {
+				if got := w.Header().Get(k); got != tt.header.Get(k) {
+					t.Errorf("got %s %q, want %q", k, got, tt.header.Get(k))
+				}
+			}
+
+			// back to an Error, the retry delay is rounded up to the second
+			rerr := e.Trusted(e.FromHTTPResponse(w.Result())).(*e.Error)
+			if rerr.Code() != e.CodeResourceExhausted || rerr.Message() != want.Detail {
+				t.Errorf("got %v %q", rerr.Code(), rerr.Message())
+			}
+			detail := tt.detail
+			if _, ok := detail.(e.RetryInfo); ok {
+				detail = e.RetryInfo{Delay: time.Duration(want.RetryAfter) * time.Second}
+			}
+			if got := rerr.Details(); !reflect.DeepEqual(got, []e.Detail{detail}) {
+				t.Errorf("got details %#v, want %#v", got, detail)
+			}
+		})
+	}
+}
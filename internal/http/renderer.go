@@ -3,10 +3,10 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
 	e "go-misc/internal/errors"
 )
@@ -27,31 +27,22 @@ func EncodeResponse(w http.ResponseWriter, v interface{}) error {
 	return nil
 }
 
+// EncodeError writes err as an RFC 7807 application/problem+json body.
 func EncodeError(ctx context.Context, w http.ResponseWriter, err error) {
 	if err == nil {
 		panic("error: err cannot be nil")
 	}
-	code := http.StatusInternalServerError
 	// log the error by adding the msg to the logEntry
 	LogEntryError(ctx, err)
 
-	var ierr *e.Error
-	if errors.As(err, &ierr) {
-		code = e.HttpStatus(ierr.Code())
-		w.WriteHeader(code)
-		json.NewEncoder(w).Encode(struct {
-			Err string `json:"error,omitempty"`
-		}{Err: ierr.Error()})
-		return
+	p := NewProblem(ctx, err)
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	if p.Language != "" {
+		w.Header().Set("Content-Language", p.Language)
 	}
-
-	var ierrs *e.Errors
-	if errors.As(err, &ierrs) {
-		code = e.HttpStatus(ierrs.Code())
-		w.WriteHeader(code)
-		json.NewEncoder(w).Encode(struct {
-			Errs []string `json:"errors,omitempty"`
-		}{Errs: ierrs.Errors()})
-		return
+	if p.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(p.RetryAfter))
 	}
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
 }
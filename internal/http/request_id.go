@@ -3,8 +3,9 @@ package http
 import (
 	"context"
 	"net/http"
+	"strings"
 
-	"github.com/google/uuid"
+	"go-misc/internal/requestid"
 )
 
 type contextKey int
@@ -17,13 +18,67 @@ const (
 	ContextKeyLogEntry
 )
 
-func RequestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		id := uuid.New()
-		ctx = context.WithValue(ctx, ContextKeyRequestID, id.String())
-		r = r.WithContext(ctx)
+// HeaderRequestID is the default header holding the request ID.
+const HeaderRequestID = "X-Request-ID"
 
-		next.ServeHTTP(w, r)
-	})
+type requestIDOptions struct {
+	header    string              // incoming header holding the request ID, the ID is echoed back in it
+	generator requestid.Generator // used when the incoming request ID is missing or invalid
+	maxLength int                 // maximum length of an incoming request ID
+}
+
+type RequestIDOption func(*requestIDOptions)
+
+func evaluateRequestIDOptions(opts []RequestIDOption) *requestIDOptions {
+	opt := &requestIDOptions{
+		header:    HeaderRequestID,
+		generator: requestid.UUIDv4,
+		maxLength: requestid.MaxLength,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+func RequestID(opts ...RequestIDOption) func(next http.Handler) http.Handler {
+	o := evaluateRequestIDOptions(opts)
+	// a traceparent is not echoed back as is, only its trace-id
+	echo := o.header
+	if strings.EqualFold(echo, requestid.KeyTraceparent) {
+		echo = HeaderRequestID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := requestid.FromIncoming(o.header, r.Header.Get(o.header), o.maxLength)
+			if !ok {
+				id = o.generator()
+			}
+			w.Header().Set(echo, id)
+			r = r.WithContext(context.WithValue(r.Context(), ContextKeyRequestID, id))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithRequestIDHeader is a functional option to read the request ID from another header.
+// "traceparent" can be used to take the trace-id of a W3C Trace Context.
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.header = header
+	}
+}
+
+// WithRequestIDGenerator is a functional option to use another generator (requestid.UUIDv4, requestid.UUIDv7, requestid.ULID...).
+func WithRequestIDGenerator(g requestid.Generator) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.generator = g
+	}
+}
+
+func WithRequestIDMaxLength(n int) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.maxLength = n
+	}
 }
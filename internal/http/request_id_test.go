@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	generate := func() string { return "generated" }
+
+	tests := []struct {
+		name   string
+		opts   []RequestIDOption
+		header string
+		v      string
+		want   string
+		echo   string // header echoing the ID
+	}{
+		{"incoming", nil, HeaderRequestID, "req-1", "req-1", HeaderRequestID},
+		{"missing", nil, "", "", "generated", HeaderRequestID},
+		{"invalid", nil, HeaderRequestID, "req 1", "generated", HeaderRequestID},
+		{"too long", []RequestIDOption{WithRequestIDMaxLength(4)}, HeaderRequestID, "req-1", "generated", HeaderRequestID},
+		{"other header", []RequestIDOption{WithRequestIDHeader("X-Correlation-ID")}, "X-Correlation-ID", "req-1", "req-1", "X-Correlation-ID"},
+		{"not the other header", []RequestIDOption{WithRequestIDHeader("X-Correlation-ID")}, HeaderRequestID, "req-1", "generated", "X-Correlation-ID"},
+		{"traceparent", []RequestIDOption{WithRequestIDHeader("traceparent")}, "traceparent", "00-" + traceID + "-00f067aa0ba902b7-01", traceID, HeaderRequestID},
+		{"invalid traceparent", []RequestIDOption{WithRequestIDHeader("traceparent")}, "traceparent", "00-" + traceID + "-0000000000000000-01", "generated", HeaderRequestID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RequestID(append([]RequestIDOption{WithRequestIDGenerator(generate)}, tt.opts...)...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = r.Context().Value(ContextKeyRequestID).(string)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got != tt.want {
+				t.Errorf("got request ID %q, want %q", got, tt.want)
+			}
+			if echo := w.Header().Get(tt.echo); echo != tt.want {
+				t.Errorf("got %s %q, want %q", tt.echo, echo, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go-misc/internal/http"
+
+type traceOptions struct {
+	tp         trace.TracerProvider
+	propagator propagation.TextMapPropagator // W3C traceparent and tracestate by default
+}
+
+type TraceOption func(*traceOptions)
+
+func evaluateTraceOptions(opts []TraceOption) *traceOptions {
+	opt := &traceOptions{
+		tp:         otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// Trace starts a server span per request, continuing the trace of the incoming traceparent/tracestate if any.
+// It must be placed before Logger so the trace_id and span_id are added to the log entry.
+func Trace(opts ...TraceOption) func(next http.Handler) http.Handler {
+	o := evaluateTraceOptions(opts)
+	tracer := o.tp.Tracer(tracerName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := o.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := r.URL.Path
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if tpl, err := cr.GetPathTemplate(); err == nil {
+					route = tpl
+				}
+			}
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", r.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(r.Method),
+					semconv.HTTPRoute(route),
+				),
+			)
+			defer span.End()
+
+			ww := &wWriter{w, false, http.StatusOK, 0}
+			next.ServeHTTP(reimplement(ww), r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPStatusCode(ww.code))
+			if ww.code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(ww.code))
+			}
+		})
+	}
+}
+
+// Transport returns an http.RoundTripper starting a client span per outbound request
+// and propagating it in the request headers.
+func Transport(base http.RoundTripper, opts ...TraceOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	o := evaluateTraceOptions(opts)
+	return &transport{base, o.tp.Tracer(tracerName), o.propagator}
+}
+
+type transport struct {
+	base       http.RoundTripper
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.URLFull(r.URL.String()),
+		),
+	)
+	defer span.End()
+
+	// RoundTrip must not modify the request
+	r = r.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+func WithTracerProvider(tp trace.TracerProvider) TraceOption {
+	return func(o *traceOptions) {
+		o.tp = tp
+	}
+}
+
+func WithPropagator(p propagation.TextMapPropagator) TraceOption {
+	return func(o *traceOptions) {
+		o.propagator = p
+	}
+}
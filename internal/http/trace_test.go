@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       int
+		wantStatus codes.Code
+	}{
+		{"ok", http.StatusOK, codes.Unset},
+		{"client error", http.StatusNotFound, codes.Unset},
+		{"server error", http.StatusInternalServerError, codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+			defer tp.Shutdown(context.Background())
+
+			var traceparent string
+			srv := httptest.NewServer(Trace(WithTracerProvider(tp))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparent = r.Header.Get("traceparent")
+				w.WriteHeader(tt.code)
+			})))
+			defer srv.Close()
+
+			client := &http.Client{Transport: Transport(nil, WithTracerProvider(tp))}
+			resp, err := client.Get(srv.URL + "/say")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			stubs := exp.GetSpans()
+			if len(stubs) != 2 {
+				t.Fatalf("%d spans, want 2", len(stubs))
+			}
+			var cs, ss tracetest.SpanStub
+			for _, s := range stubs {
+				switch s.SpanKind {
+				case trace.SpanKindClient:
+					cs = s
+				case trace.SpanKindServer:
+					ss = s
+				}
+			}
+
+			if traceparent == "" {
+				t.Error("no traceparent propagated")
+			}
+			if ss.Parent.SpanID() != cs.SpanContext.SpanID() || ss.SpanContext.TraceID() != cs.SpanContext.TraceID() {
+				t.Errorf("server span %v is not a child of client span %v", ss.Parent, cs.SpanContext)
+			}
+			if ss.Name != "GET /say" {
+				t.Errorf("server span %q, want %q", ss.Name, "GET /say")
+			}
+			for _, s := range []tracetest.SpanStub{cs, ss} {
+				if s.Status.Code != tt.wantStatus {
+					t.Errorf("%v span status %v, want %v", s.SpanKind, s.Status.Code, tt.wantStatus)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package requestid
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// MaxLength is the default maximum length of an incoming request ID.
+const MaxLength = 128
+
+// KeyTraceparent is the W3C Trace Context header/metadata key, the trace-id it holds is used as request ID.
+const KeyTraceparent = "traceparent"
+
+// Generator returns a new request ID.
+type Generator func() string
+
+// UUIDv4 generates a random UUID (RFC 4122).
+func UUIDv4() string {
+	return uuid.New().String()
+}
+
+// UUIDv7 generates a time ordered UUID, falls back to UUIDv4 if it fails.
+func UUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return UUIDv4()
+	}
+	return id.String()
+}
+
+// ULID generates a lexicographically sortable identifier.
+func ULID() string {
+	return ulid.Make().String()
+}
+
+// FromIncoming returns the request ID carried by the value v of the incoming header/metadata key,
+// and false if v is not a valid request ID (empty, too long or with unexpected characters).
+func FromIncoming(key, v string, maxLength int) (string, bool) {
+	if strings.EqualFold(key, KeyTraceparent) {
+		return traceID(v)
+	}
+	if v == "" || len(v) > maxLength {
+		return "", false
+	}
+	for _, c := range v {
+		if !validChar(c) {
+			return "", false
+		}
+	}
+	return v, true
+}
+
+// traceID returns the trace-id of a traceparent "{version}-{trace-id}-{parent-id}-{flags}",
+// later versions may append fields. The fields are lowercase hex, version ff and all zero IDs are invalid.
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func traceID(v string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || parts[0] == "00" && len(parts) != 4 || parts[0] == "ff" {
+		return "", false
+	}
+	for i, n := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != n || !isHex(parts[i]) {
+			return "", false
+		}
+	}
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func validChar(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	case c == '-', c == '_', c == '.', c == ':':
+		return true
+	default:
+		return false
+	}
+}
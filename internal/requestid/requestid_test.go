@@ -0,0 +1,80 @@
+package requestid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestFromIncoming(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name string
+		key  string
+		v    string
+		want string // invalid if empty
+	}{
+		{"id", "X-Request-ID", "req-1_a.b:c", "req-1_a.b:c"},
+		{"uuid", "X-Request-ID", "0b9f1b38-1d2c-4c4f-9b5e-5b2f8c6b1f51", "0b9f1b38-1d2c-4c4f-9b5e-5b2f8c6b1f51"},
+		{"empty", "X-Request-ID", "", ""},
+		{"max length", "X-Request-ID", strings.Repeat("a", MaxLength), strings.Repeat("a", MaxLength)},
+		{"too long", "X-Request-ID", strings.Repeat("a", MaxLength+1), ""},
+		{"space", "X-Request-ID", "req 1", ""},
+		{"header injection", "X-Request-ID", "req\r\nX-Admin: 1", ""},
+		{"non ascii", "X-Request-ID", "réq", ""},
+
+		{"traceparent", "traceparent", "00-" + traceID + "-" + parentID + "-01", traceID},
+		{"traceparent key case", "Traceparent", " 00-" + traceID + "-" + parentID + "-00 ", traceID},
+		{"later version", "traceparent", "01-" + traceID + "-" + parentID + "-01-extra", traceID},
+		{"version 00 extra field", "traceparent", "00-" + traceID + "-" + parentID + "-01-extra", ""},
+		{"version ff", "traceparent", "ff-" + traceID + "-" + parentID + "-01", ""},
+		{"version not hex", "traceparent", "0g-" + traceID + "-" + parentID + "-01", ""},
+		{"missing field", "traceparent", "00-" + traceID + "-" + parentID, ""},
+		{"short trace-id", "traceparent", "00-" + traceID[1:] + "-" + parentID + "-01", ""},
+		{"uppercase trace-id", "traceparent", "00-" + strings.ToUpper(traceID) + "-" + parentID + "-01", ""},
+		{"zero trace-id", "traceparent", "00-" + strings.Repeat("0", 32) + "-" + parentID + "-01", ""},
+		{"parent-id not hex", "traceparent", "00-" + traceID + "-" + "00f067aa0ba902bz" + "-01", ""},
+		{"zero parent-id", "traceparent", "00-" + traceID + "-" + strings.Repeat("0", 16) + "-01", ""},
+		{"flags not hex", "traceparent", "00-" + traceID + "-" + parentID + "-0x", ""},
+		{"request id", "traceparent", "req-1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromIncoming(tt.key, tt.v, MaxLength)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("got %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     Generator
+		valid func(id string) bool
+	}{
+		{"uuidv4", UUIDv4, func(id string) bool { u, err := uuid.Parse(id); return err == nil && u.Version() == 4 }},
+		{"uuidv7", UUIDv7, func(id string) bool { u, err := uuid.Parse(id); return err == nil && u.Version() == 7 }},
+		{"ulid", ULID, func(id string) bool { _, err := ulid.ParseStrict(id); return err == nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := tt.g()
+			if !tt.valid(id) {
+				t.Errorf("invalid id %q", id)
+			}
+			if _, ok := FromIncoming("X-Request-ID", id, MaxLength); !ok {
+				t.Errorf("generated id %q rejected as incoming id", id)
+			}
+			if id == tt.g() {
+				t.Error("same id generated twice")
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by the commands of a closed client.
+var ErrClosed = errors.New("resp: client closed")
+
+type options struct {
+	// timeout bounds a command, dial included, when the context has no deadline.
+	timeout time.Duration
+
+	// poolSize is the maximum number of idle connections.
+	poolSize int
+
+	// password is sent with AUTH on every new connection, if not empty.
+	password string
+}
+
+type Option func(*options)
+
+func evaluateOptions(opts []Option) *options {
+	opt := &options{
+		timeout:  500 * time.Millisecond,
+		poolSize: 16,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return opt
+}
+
+// Client sends commands to a RESP server over a pool of connections, it is safe for concurrent use.
+type Client struct {
+	addr   string
+	o      *options
+	dialer net.Dialer
+	idle   chan *conn
+	closed atomic.Bool
+}
+
+type conn struct {
+	c net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewClient returns a client of the server at addr ("host:port"), connections are opened on demand.
+func NewClient(addr string, opts ...Option) *Client {
+	o := evaluateOptions(opts)
+	return &Client{addr: addr, o: o, idle: make(chan *conn, o.poolSize)}
+}
+
+// WithTimeout bounds the commands whose context has no deadline (500ms by default).
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithPoolSize sets the maximum number of idle connections (16 by default).
+func WithPoolSize(n int) Option {
+	return func(o *options) {
+		o.poolSize = n
+	}
+}
+
+// WithPassword authenticates the connections with AUTH.
+func WithPassword(password string) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+// Get returns the value of key, false if there is none.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := c.do(ctx, []byte("GET"), []byte(key))
+	if err != nil {
+		return nil, false, err
+	}
+	if v.kind != '$' {
+		return nil, false, fmt.Errorf("resp: GET: unexpected reply %q", v.kind)
+	}
+	return v.bulk, !v.null, nil
+}
+
+// MGet returns the values of keys, nil for the missing ones.
+func (c *Client) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	args := make([][]byte, 0, 1+len(keys))
+	args = append(args, []byte("MGET"))
+	for _, k := range keys {
+		args = append(args, []byte(k))
+	}
+	v, err := c.do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if v.kind != '*' || len(v.array) != len(keys) {
+		return nil, fmt.Errorf("resp: MGET: unexpected reply %q of %d values", v.kind, len(v.array))
+	}
+	values := make([][]byte, len(keys))
+	for i, e := range v.array {
+		if e.kind == '$' && !e.null {
+			values[i] = e.bulk
+		}
+	}
+	return values, nil
+}
+
+// Set sets the value of key, expiring after ttl (rounded up to the second, with EX) if ttl > 0.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := [][]byte{[]byte("SET"), []byte(key), value}
+	if ttl > 0 {
+		secs := int64((ttl + time.Second - 1) / time.Second)
+		args = append(args, []byte("EX"), []byte(strconv.FormatInt(secs, 10)))
+	}
+	v, err := c.do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if v.kind != '+' {
+		return fmt.Errorf("resp: SET: unexpected reply %q", v.kind)
+	}
+	return nil
+}
+
+// Del removes keys, it returns the number of keys that existed.
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	args := make([][]byte, 0, 1+len(keys))
+	args = append(args, []byte("DEL"))
+	for _, k := range keys {
+		args = append(args, []byte(k))
+	}
+	v, err := c.do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	if v.kind != ':' {
+		return 0, fmt.Errorf("resp: DEL: unexpected reply %q", v.kind)
+	}
+	return v.int, nil
+}
+
+// Close closes the idle connections and the busy ones once their command is done, later commands fail with ErrClosed.
+func (c *Client) Close() error {
+	c.closed.Store(true)
+	var errs []error
+	for {
+		select {
+		case cn := <-c.idle:
+			errs = append(errs, cn.c.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// do sends a command and reads its reply, an error reply is returned as an Error.
+// The connection is discarded on any other error, it may be out of sync.
+func (c *Client) do(ctx context.Context, args ...[]byte) (value, error) {
+	if c.closed.Load() {
+		return value{}, ErrClosed
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.o.timeout)
+		defer cancel()
+	}
+	cn, err := c.get(ctx)
+	if err != nil {
+		return value{}, err
+	}
+
+	v, err := cn.do(ctx, args...)
+	if err != nil {
+		cn.c.Close()
+		return value{}, err
+	}
+	c.put(cn)
+	if v.kind == '-' {
+		return value{}, Error(v.str)
+	}
+	return v, nil
+}
+
+func (cn *conn) do(ctx context.Context, args ...[]byte) (value, error) {
+	deadline, _ := ctx.Deadline()
+	if err := cn.c.SetDeadline(deadline); err != nil {
+		return value{}, err
+	}
+	if err := writeCommand(cn.w, args...); err != nil {
+		return value{}, err
+	}
+	return readValue(cn.r)
+}
+
+// get returns an idle connection or dials a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	nc, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{c: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.o.password != "" {
+		v, err := cn.do(ctx, []byte("AUTH"), []byte(c.o.password))
+		if err == nil && v.kind == '-' {
+			err = Error(v.str)
+		}
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// put returns cn to the pool, it is closed if the pool is full or the client closed.
+func (c *Client) put(cn *conn) {
+	if c.closed.Load() {
+		cn.c.Close()
+		return
+	}
+	select {
+	case c.idle <- cn:
+		// Close may have drained the pool meanwhile
+		if c.closed.Load() {
+			c.Close()
+		}
+	default:
+		cn.c.Close()
+	}
+}
@@ -0,0 +1,118 @@
+// Package resp is a minimal client of the RESP protocol (Redis and compatible servers) for the commands used by
+// the cache, with an in-process Server for tests.
+// https://redis.io/docs/reference/protocol-spec/
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Error is an error reply of the server, e.g. "ERR unknown command".
+type Error string
+
+func (e Error) Error() string {
+	return "resp: " + string(e)
+}
+
+// maxBulkLen bounds the allocations when reading a bulk string, it is the limit of Redis.
+const maxBulkLen = 512 << 20
+
+// value is a RESP2 reply: a simple string, an error, an integer, a bulk string (nil if null) or an array.
+type value struct {
+	kind  byte // '+', '-', ':', '$' or '*'
+	str   string
+	int   int64
+	bulk  []byte
+	null  bool
+	array []value
+}
+
+// writeCommand writes args as an array of bulk strings.
+func writeCommand(w *bufio.Writer, args ...[]byte) error {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(len(args)))
+	w.WriteString("\r\n")
+	for _, a := range args {
+		writeBulk(w, a)
+	}
+	return w.Flush()
+}
+
+func writeBulk(w *bufio.Writer, b []byte) {
+	w.WriteByte('$')
+	w.WriteString(strconv.Itoa(len(b)))
+	w.WriteString("\r\n")
+	w.Write(b)
+	w.WriteString("\r\n")
+}
+
+func readValue(r *bufio.Reader) (value, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return value{}, err
+	}
+	line, err := readLine(r)
+	if err != nil {
+		return value{}, err
+	}
+
+	v := value{kind: kind}
+	switch kind {
+	case '+', '-':
+		v.str = line
+	case ':':
+		if v.int, err = strconv.ParseInt(line, 10, 64); err != nil {
+			return value{}, fmt.Errorf("resp: bad integer %q", line)
+		}
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < -1 || n > maxBulkLen {
+			return value{}, fmt.Errorf("resp: bad bulk length %q", line)
+		}
+		if n == -1 {
+			v.null = true
+			return v, nil
+		}
+		v.bulk = make([]byte, n+2)
+		if _, err := io.ReadFull(r, v.bulk); err != nil {
+			return value{}, err
+		}
+		v.bulk = v.bulk[:n]
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < -1 {
+			return value{}, fmt.Errorf("resp: bad array length %q", line)
+		}
+		if n == -1 {
+			v.null = true
+			return v, nil
+		}
+		v.array = make([]value, 0, min(n, 1024))
+		for i := 0; i < n; i++ {
+			e, err := readValue(r)
+			if err != nil {
+				return value{}, err
+			}
+			v.array = append(v.array, e)
+		}
+	default:
+		return value{}, fmt.Errorf("resp: unknown type %q", kind)
+	}
+	return v, nil
+}
+
+// readLine reads a line terminated by CRLF, without it.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("resp: line not terminated by CRLF")
+	}
+	return line[:len(line)-2], nil
+}
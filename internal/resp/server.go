@@ -0,0 +1,192 @@
+package resp
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-process RESP server for tests, like httptest.Server, no real Redis required.
+// It supports PING, AUTH (accepting any password), GET, SET (with EX or PX), DEL and MGET.
+type Server struct {
+	l     net.Listener
+	mtx   sync.Mutex
+	data  map[string]item
+	calls map[string]int
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+type item struct {
+	value  []byte
+	expiry time.Time // zero if none
+}
+
+// NewServer starts a server listening on a random port of the loopback interface.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{l: l, data: make(map[string]item), calls: make(map[string]int), conns: make(map[net.Conn]struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address of the server, for NewClient.
+func (s *Server) Addr() string {
+	return s.l.Addr().String()
+}
+
+// Len returns the number of keys not expired.
+func (s *Server) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	n := 0
+	for _, it := range s.data {
+		if !it.expired(time.Now()) {
+			n++
+		}
+	}
+	return n
+}
+
+// Calls returns the number of cmd commands received, e.g. "MGET".
+func (s *Server) Calls(cmd string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.calls[strings.ToUpper(cmd)]
+}
+
+// Close stops the server and closes the open connections.
+func (s *Server) Close() error {
+	err := s.l.Close()
+	s.mtx.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mtx.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mtx.Lock()
+		s.conns[c] = struct{}{}
+		s.mtx.Unlock()
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mtx.Lock()
+		delete(s.conns, c)
+		s.mtx.Unlock()
+		c.Close()
+	}()
+	r, w := bufio.NewReader(c), bufio.NewWriter(c)
+	for {
+		v, err := readValue(r)
+		if err != nil {
+			return
+		}
+		if v.kind != '*' || len(v.array) == 0 {
+			writeError(w, "ERR protocol error: expected an array of bulk strings")
+		} else {
+			args := make([][]byte, 0, len(v.array))
+			for _, a := range v.array {
+				args = append(args, a.bulk)
+			}
+			s.exec(w, args)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, args [][]byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	cmd := strings.ToUpper(string(args[0]))
+	s.calls[cmd]++
+	switch {
+	case cmd == "PING":
+		w.WriteString("+PONG\r\n")
+	case cmd == "AUTH":
+		w.WriteString("+OK\r\n")
+	case cmd == "GET" && len(args) == 2:
+		it, ok := s.data[string(args[1])]
+		if !ok || it.expired(now) {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		writeBulk(w, it.value)
+	case cmd == "MGET" && len(args) >= 2:
+		w.WriteString("*" + strconv.Itoa(len(args)-1) + "\r\n")
+		for _, k := range args[1:] {
+			it, ok := s.data[string(k)]
+			if !ok || it.expired(now) {
+				w.WriteString("$-1\r\n")
+				continue
+			}
+			writeBulk(w, it.value)
+		}
+	case cmd == "SET" && (len(args) == 3 || len(args) == 5):
+		it := item{value: append([]byte(nil), args[2]...)}
+		if len(args) == 5 {
+			n, err := strconv.ParseInt(string(args[4]), 10, 64)
+			if err != nil || n <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			switch strings.ToUpper(string(args[3])) {
+			case "EX":
+				it.expiry = now.Add(time.Duration(n) * time.Second)
+			case "PX":
+				it.expiry = now.Add(time.Duration(n) * time.Millisecond)
+			default:
+				writeError(w, "ERR syntax error")
+				return
+			}
+		}
+		s.data[string(args[1])] = it
+		w.WriteString("+OK\r\n")
+	case cmd == "DEL" && len(args) >= 2:
+		n := 0
+		for _, k := range args[1:] {
+			if it, ok := s.data[string(k)]; ok {
+				if !it.expired(now) {
+					n++
+				}
+				delete(s.data, string(k))
+			}
+		}
+		w.WriteString(":" + strconv.Itoa(n) + "\r\n")
+	default:
+		writeError(w, "ERR unknown command or wrong number of arguments for '"+string(args[0])+"'")
+	}
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + msg + "\r\n")
+}
+
+func (it item) expired(now time.Time) bool {
+	return !it.expiry.IsZero() && !now.Before(it.expiry)
+}